@@ -8,6 +8,11 @@ export const UploadFileSchema = t.object({
     filePath: t.string().optional(),
 })
 
+export const DeleteFileSchema = t.object({
+    filePath: t.string().optional(),
+    purge: t.enum(["true", "false"]).optional(),
+})
+
 export const GetUploadFileSchema = t.object({
     replaceFileName: t.string().optional(),
     filePath: t.string().optional(),
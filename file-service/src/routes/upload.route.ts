@@ -3,7 +3,7 @@ import { AppRouter, t } from "../my-router"
 import path from 'path'
 import fs from 'fs'
 import { UploadedFile } from 'express-fileupload'
-import { fileSchema, GetUploadFileSchema, IUploadFileResponse, UploadFileSchema } from "../models/upload.model"
+import { DeleteFileSchema, fileSchema, GetUploadFileSchema, IUploadFileResponse, UploadFileSchema } from "../models/upload.model"
 import { HttpLogger } from "../logger"
 import { FileRepository } from "../repository/file.repository"
 import { FileService } from "../service/file.service"
@@ -143,12 +143,24 @@ appRouter.delete('/file/:filename', async ({ params: { filename }, query, req })
         filePath = path.join(rootDir, query.filePath)
     }
     const data = await fileService.deleteFile(filename, filePath, logger)
+    if (!data.data) {
+        return {
+            statusCode: 400,
+            message: 'file not found'
+        }
+    }
+
+    // purge removes the file from disk now instead of after the 30 days
+    const fullPath = path.join(filePath, filename)
+    if (query?.purge === 'true' && fs.existsSync(fullPath)) {
+        fs.unlinkSync(fullPath)
+    }
     return {
-        statusCode: data ? 200 : 400,
-        message: data ? 'file deleted' : 'file not found'
+        statusCode: 200,
+        message: 'file deleted'
     }
 }, {
-    query: UploadFileSchema
+    query: DeleteFileSchema
 })
 
 
@@ -1,7 +1,6 @@
 package http_service
 
 import (
-	"bytes"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -92,9 +91,11 @@ type Options struct {
 	headers map[string]string
 }
 
+// FormFile is a file of a multipart form. File is closed once sent when it
+// is an io.Closer.
 type FormFile struct {
 	Name       string
-	File       multipart.File
+	File       io.Reader
 	FileHeader *multipart.FileHeader
 }
 
@@ -155,6 +156,88 @@ func HttpGetClient[TResponse any](opt *Options) (result HttpResponse[*TResponse]
 	return handleResponse(resp, result, opt.URL)
 }
 
+// HttpDeleteClient sends a DELETE request to opt.URL and decodes the JSON
+// response
+func HttpDeleteClient[TResponse any](opt *Options) (result HttpResponse[*TResponse], err error) {
+	result.StatusCode = http.StatusInternalServerError
+
+	u, err := url.Parse(opt.URL)
+	if err != nil {
+		return handleError(result, fmt.Sprintf("Error parsing URL %s: %v\n", opt.URL, err), err)
+	}
+
+	addQueryParams(u, opt.Param)
+
+	req, err := http.NewRequest(http.MethodDelete, u.String(), nil)
+	if err != nil {
+		return handleError(result, fmt.Sprintf("Error creating request for URL %s: %v\n", opt.URL, err), err)
+	}
+
+	setHeaders(req, opt.headers)
+
+	if opt.Timeout == 0 {
+		opt.Timeout = 30
+	}
+
+	client := &http.Client{Timeout: opt.Timeout * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return handleError(result, fmt.Sprintf("Error sending request to URL %s: %v\n", opt.URL, err), err)
+	}
+	defer resp.Body.Close()
+
+	return handleResponse(resp, result, opt.URL)
+}
+
+// HttpGetFile downloads the raw body of opt.URL, such as an image served by
+// the file-service. Non-2xx responses are returned as an error.
+func HttpGetFile(opt *Options) (result HttpResponse[[]byte], err error) {
+	result.StatusCode = http.StatusInternalServerError
+
+	req, err := http.NewRequest(http.MethodGet, opt.URL, nil)
+	if err != nil {
+		log.Printf("Error creating request for URL %s: %v\n", opt.URL, err)
+		result.Message = err.Error()
+		return result, err
+	}
+
+	for key, value := range opt.headers {
+		req.Header.Set(key, value)
+	}
+
+	if opt.Timeout == 0 {
+		opt.Timeout = 30
+	}
+
+	client := &http.Client{Timeout: opt.Timeout * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		log.Printf("Error fetching URL %s: %v\n", opt.URL, err)
+		result.Message = err.Error()
+		return result, err
+	}
+	defer resp.Body.Close()
+
+	result.StatusCode = resp.StatusCode
+	result.Message = resp.Status
+	result.Description = resp.Header.Get(ContentType)
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		log.Printf("Error reading response from URL %s: %v\n", opt.URL, err)
+		result.Message = err.Error()
+		return result, err
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		result.Description = string(body)
+		return result, fmt.Errorf("fetching URL %s: %s", opt.URL, resp.Status)
+	}
+
+	result.Data = body
+	return result, nil
+}
+
 func handleError[TResponse any](result HttpResponse[*TResponse], message string, err error) (HttpResponse[*TResponse], error) {
 	log.Println(message, err)
 	result.Message = err.Error()
@@ -263,13 +346,16 @@ func HttpPostClient[TResponse any, TBody any](url string, payload TBody, opt Opt
 	return result
 }
 
+// HttpPostForm posts opt as a multipart form. The form is streamed while it
+// is sent, so large files are never held in memory.
 func HttpPostForm[TResponse any](opt OptionPostForm) (result HttpResponse[*TResponse]) {
-	payload, writer, err := createMultipartPayload(opt)
-	if err != nil {
-		result.StatusCode = http.StatusInternalServerError
-		result.Message = err.Error()
-		return result
-	}
+	payload, pw := io.Pipe()
+	// Unblocks the writer when the request ends before the form is sent
+	defer payload.Close()
+	writer := multipart.NewWriter(pw)
+	go func() {
+		pw.CloseWithError(writeMultipartPayload(writer, opt))
+	}()
 
 	req, err := http.NewRequest(http.MethodPost, opt.URL, payload)
 	if err != nil {
@@ -318,9 +404,18 @@ func HttpPostForm[TResponse any](opt OptionPostForm) (result HttpResponse[*TResp
 	return result
 }
 
-func createMultipartPayload(opt OptionPostForm) (*bytes.Buffer, *multipart.Writer, error) {
-	payload := new(bytes.Buffer)
-	writer := multipart.NewWriter(payload)
+func writeMultipartPayload(writer *multipart.Writer, opt OptionPostForm) error {
+	for _, formFile := range opt.FormFiles {
+		if closer, ok := formFile.File.(io.Closer); ok {
+			defer closer.Close()
+		}
+	}
+	for key, value := range opt.Fields {
+		if err := writer.WriteField(key, value); err != nil {
+			log.Println("Error writing field.", err)
+			return err
+		}
+	}
 	for _, formFile := range opt.FormFiles {
 		if formFile.Name == "" {
 			formFile.Name = "file"
@@ -328,24 +423,16 @@ func createMultipartPayload(opt OptionPostForm) (*bytes.Buffer, *multipart.Write
 		part, err := writer.CreateFormFile(formFile.Name, formFile.FileHeader.Filename)
 		if err != nil {
 			log.Println("Error creating form file.", err)
-			return nil, nil, err
+			return err
 		}
-		_, err = io.Copy(part, formFile.File)
-		if err != nil {
+		if _, err := io.Copy(part, formFile.File); err != nil {
 			log.Println("Error copying file.", err)
-			return nil, nil, err
-		}
-		defer formFile.File.Close()
-	}
-	for key, value := range opt.Fields {
-		if err := writer.WriteField(key, value); err != nil {
-			log.Println("Error writing field.", err)
-			return nil, nil, err
+			return err
 		}
 	}
 	if err := writer.Close(); err != nil {
 		log.Println("Error closing writer.", err)
-		return nil, nil, err
+		return err
 	}
-	return payload, writer, nil
+	return nil
 }
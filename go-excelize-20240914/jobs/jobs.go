@@ -0,0 +1,320 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sing3demons/20240914/excelize/mlog"
+	"github.com/sing3demons/20240914/excelize/storage"
+	"github.com/sing3demons/20240914/excelize/xlsx"
+)
+
+type Status string
+
+const (
+	StatusQueued  Status = "queued"
+	StatusRunning Status = "running"
+	StatusDone    Status = "done"
+	StatusFailed  Status = "failed"
+)
+
+var (
+	ErrQueueFull = errors.New("export queue is full")
+	ErrClosed    = errors.New("export queue is closed")
+	ErrNotFound  = errors.New("export job not found")
+	ErrNotReady  = errors.New("export is not ready")
+	ErrFailed    = errors.New("export failed")
+	ErrExpired   = errors.New("export file has expired")
+)
+
+// Result is what a task produces: the export to write and the row counts
+// reported with the job
+type Result struct {
+	Exporter xlsx.Exporter
+	// Name is the download name without extension
+	Name   string
+	Rows   int
+	Failed int
+}
+
+// Task generates an export, reporting its progress through p. ctx carries
+// the logger of the request that submitted it.
+type Task func(ctx context.Context, p *Progress) (Result, error)
+
+// Progress counts the steps of a running task. A nil Progress ignores them,
+// so the same code can run outside of a job.
+type Progress struct {
+	total atomic.Int64
+	done  atomic.Int64
+}
+
+// SetTotal sets the number of steps the task will take
+func (p *Progress) SetTotal(n int) {
+	if p != nil {
+		p.total.Store(int64(n))
+	}
+}
+
+// Step marks n steps as done
+func (p *Progress) Step(n int) {
+	if p != nil {
+		p.done.Add(int64(n))
+	}
+}
+
+// Job is the state of an export as reported by GET /exports/{id}
+type Job struct {
+	ID     string `json:"id"`
+	Status Status `json:"status"`
+	// Progress is the percentage of steps done
+	Progress   float64    `json:"progress"`
+	Done       int64      `json:"done"`
+	Total      int64      `json:"total"`
+	Rows       int        `json:"rows"`
+	Failed     int        `json:"failed"`
+	Error      string     `json:"error,omitempty"`
+	FileName   string     `json:"fileName,omitempty"`
+	Size       int64      `json:"size,omitempty"`
+	CreatedAt  time.Time  `json:"createdAt"`
+	StartedAt  *time.Time `json:"startedAt,omitempty"`
+	FinishedAt *time.Time `json:"finishedAt,omitempty"`
+}
+
+type job struct {
+	Job
+	ctx         context.Context
+	task        Task
+	progress    Progress
+	key         string
+	contentType string
+}
+
+// Options configures a Manager
+type Options struct {
+	// Workers is the number of exports generated at the same time
+	Workers int
+	// QueueSize is the number of exports waiting for a worker before
+	// Submit fails with ErrQueueFull
+	QueueSize int
+	// Storage keeps the files, a directory in os.TempDir() when nil
+	Storage storage.Storage
+	// Retention is how long finished jobs are kept, forever when 0. It
+	// should match the TTL of Storage, after which their files are gone.
+	Retention time.Duration
+}
+
+// Manager runs export tasks on a pool of background workers
+type Manager struct {
+	mu        sync.RWMutex
+	jobs      map[string]*job
+	queue     chan *job
+	closed    bool
+	storage   storage.Storage
+	retention time.Duration
+	wg        sync.WaitGroup
+}
+
+func NewManager(opt Options) (*Manager, error) {
+	if opt.Workers <= 0 {
+		opt.Workers = 1
+	}
+	if opt.Storage == nil {
+		local, err := storage.NewLocal(filepath.Join(os.TempDir(), "exports"), storage.Options{})
+		if err != nil {
+			return nil, err
+		}
+		opt.Storage = local
+	}
+
+	m := &Manager{
+		jobs:      map[string]*job{},
+		queue:     make(chan *job, opt.QueueSize),
+		storage:   opt.Storage,
+		retention: opt.Retention,
+	}
+	for range opt.Workers {
+		m.wg.Add(1)
+		go m.work()
+	}
+	return m, nil
+}
+
+// Submit queues task and returns its job. The job keeps running when ctx
+// is canceled.
+func (m *Manager) Submit(ctx context.Context, task Task) (Job, error) {
+	j := &job{
+		Job: Job{
+			ID:        uuid.New().String(),
+			Status:    StatusQueued,
+			CreatedAt: time.Now(),
+		},
+		ctx:  context.WithoutCancel(ctx),
+		task: task,
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.closed {
+		return Job{}, ErrClosed
+	}
+	m.prune()
+	select {
+	case m.queue <- j:
+	default:
+		return Job{}, ErrQueueFull
+	}
+	m.jobs[j.ID] = j
+	return j.snapshot(), nil
+}
+
+// Get returns the current state of a job
+func (m *Manager) Get(id string) (Job, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	j, ok := m.jobs[id]
+	if !ok {
+		return Job{}, ErrNotFound
+	}
+	return j.snapshot(), nil
+}
+
+// Open opens the file of a finished job along with its content type. It
+// fails with ErrNotReady while the job is queued or running, ErrFailed when
+// it has failed and ErrExpired once the storage has removed the file.
+func (m *Manager) Open(id string) (io.ReadCloser, Job, string, error) {
+	m.mu.RLock()
+	j, ok := m.jobs[id]
+	if !ok {
+		m.mu.RUnlock()
+		return nil, Job{}, "", ErrNotFound
+	}
+	state, key, contentType := j.snapshot(), j.key, j.contentType
+	m.mu.RUnlock()
+
+	if state.Status == StatusFailed {
+		return nil, state, "", ErrFailed
+	}
+	if state.Status != StatusDone {
+		return nil, state, "", ErrNotReady
+	}
+	r, _, err := m.storage.Open(key)
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil, state, "", ErrExpired
+	}
+	if err != nil {
+		return nil, state, "", fmt.Errorf("failed to open export: %v", err)
+	}
+	return r, state, contentType, nil
+}
+
+// prune forgets the jobs that finished longer than the retention ago. The
+// caller must hold the lock of the manager.
+func (m *Manager) prune() {
+	if m.retention <= 0 {
+		return
+	}
+	for id, j := range m.jobs {
+		if j.FinishedAt != nil && time.Since(*j.FinishedAt) > m.retention {
+			delete(m.jobs, id)
+		}
+	}
+}
+
+// Close stops accepting jobs and waits for the queued ones to finish
+func (m *Manager) Close() {
+	m.mu.Lock()
+	if !m.closed {
+		m.closed = true
+		close(m.queue)
+	}
+	m.mu.Unlock()
+	m.wg.Wait()
+}
+
+func (m *Manager) work() {
+	defer m.wg.Done()
+	for j := range m.queue {
+		m.run(j)
+	}
+}
+
+func (m *Manager) run(j *job) {
+	logger := mlog.L(j.ctx).With("job", j.ID)
+
+	m.mu.Lock()
+	started := time.Now()
+	j.Status, j.StartedAt = StatusRunning, &started
+	m.mu.Unlock()
+
+	result, stored, err := m.generate(j)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	finished := time.Now()
+	j.FinishedAt = &finished
+	if err != nil {
+		logger.Error("export job failed.", "error", err)
+		j.Status, j.Error = StatusFailed, err.Error()
+		return
+	}
+	logger.Info("export job done.", "rows", result.Rows, "failed", result.Failed, "duration", finished.Sub(started).String())
+	j.Status = StatusDone
+	j.Rows, j.Failed = result.Rows, result.Failed
+	j.FileName = result.Name + "." + result.Exporter.Extension()
+	j.Size = stored.Size
+	j.key, j.contentType = stored.Key, result.Exporter.ContentType()
+}
+
+// generate runs the task of j and stores its export under the job ID
+func (m *Manager) generate(j *job) (result Result, stored storage.Object, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("export panicked: %v", r)
+		}
+	}()
+
+	result, err = j.task(j.ctx, &j.progress)
+	if err != nil {
+		return result, stored, err
+	}
+
+	pr, pw := io.Pipe()
+	go func() {
+		// The recover of generate does not cover this goroutine
+		defer func() {
+			if r := recover(); r != nil {
+				pw.CloseWithError(fmt.Errorf("export panicked: %v", r))
+			}
+		}()
+		pw.CloseWithError(result.Exporter.Write(pw))
+	}()
+	stored, err = m.storage.Put(j.ID+"."+result.Exporter.Extension(), pr)
+	// Unblocks the writer when the storage stopped reading early
+	pr.CloseWithError(err)
+	if err != nil {
+		return result, stored, fmt.Errorf("failed to store export: %v", err)
+	}
+	return result, stored, nil
+}
+
+// snapshot copies the state of j, filling in its progress. The caller must
+// hold the lock of the manager.
+func (j *job) snapshot() Job {
+	s := j.Job
+	s.Done, s.Total = j.progress.done.Load(), j.progress.total.Load()
+	switch {
+	case s.Status == StatusDone:
+		s.Progress = 100
+	case s.Total > 0:
+		s.Progress = float64(min(s.Done, s.Total)) * 100 / float64(s.Total)
+	}
+	return s
+}
@@ -3,13 +3,16 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"log"
 	"log/slog"
 	"net/http"
 	"net/url"
 	"os"
 	"os/signal"
+	"path/filepath"
 	"runtime"
 	"strconv"
 	"strings"
@@ -19,18 +22,27 @@ import (
 
 	"github.com/joho/godotenv"
 	httpService "github.com/sing3demons/20240914/excelize/http-service"
+	"github.com/sing3demons/20240914/excelize/jobs"
 	"github.com/sing3demons/20240914/excelize/logger"
 	"github.com/sing3demons/20240914/excelize/mlog"
+	"github.com/sing3demons/20240914/excelize/storage"
 	"github.com/sing3demons/20240914/excelize/xlsx"
+	"golang.org/x/text/language"
 )
 
 type Data struct {
-	NO      int    `json:"NO"`
-	Name    string `json:"Name"`
-	Status  string `json:"Status"`
-	Message string `json:"Message"`
+	NO      int    `json:"NO" xlsx:"NO;width=8"`
+	Name    string `json:"Name" xlsx:"Name;width=30"`
+	Status  string `json:"Status" xlsx:"Status;width=12"`
+	Message string `json:"Message" xlsx:"Message;width=60"`
 }
 
+const (
+	StatusSuccess = "SUCCESS"
+	StatusFailed  = "FAILED"
+	StatusValid   = "VALID"
+)
+
 type P struct {
 	ID          string  `json:"id"`
 	Href        string  `json:"href"`
@@ -42,13 +54,13 @@ type P struct {
 }
 
 type ProductResponse struct {
-	ID          string  `json:"ID"`
-	Href        string  `json:"href"`
-	Name        string  `json:"Name"`
-	Description string  `json:"Description"`
-	Price       float64 `json:"Price"`
-	Image       string  `json:"Image"`
-	Stock       int     `json:"Stock"`
+	ID          string  `json:"ID" xlsx:"ID;width=38"`
+	Href        string  `json:"href" xlsx:"Link;width=40;link"`
+	Name        string  `json:"Name" xlsx:"Product Name"`
+	Description string  `json:"Description" xlsx:"Description"`
+	Price       float64 `json:"Price" xlsx:"Unit Price;width=15;currency=THB"`
+	Image       string  `json:"Image" xlsx:"Image;width=40;image"`
+	Stock       int     `json:"Stock" xlsx:"Stock;width=10"`
 }
 
 type DataProducts struct {
@@ -143,6 +155,10 @@ type CreateProductResponse struct {
 }
 
 type ProductHandler struct {
+	// jobs generates the exports requested through POST /exports
+	jobs *jobs.Manager
+	// storage keeps the copies saved by GET /product
+	storage storage.Storage
 }
 
 func (p *ProductHandler) UploadFile(w http.ResponseWriter, r *http.Request) {
@@ -182,11 +198,22 @@ func (p *ProductHandler) ResponseJson(w http.ResponseWriter, data interface{}, c
 }
 
 type TCreateProduct struct {
-	Name        string  `json:"name"`
-	Price       float64 `json:"price"`
-	Description string  `json:"description"`
-	Image       string  `json:"image"`
-	Stock       int     `json:"stock"`
+	Name        string  `json:"name" xlsx:"Name;required"`
+	Price       float64 `json:"price" xlsx:"Price;required"`
+	Description string  `json:"description" xlsx:"Description"`
+	Image       string  `json:"image" xlsx:"Image"`
+	Stock       int     `json:"stock" xlsx:"Stock"`
+}
+
+// Validate is called by xlsx.Read for every imported row
+func (p *TCreateProduct) Validate() error {
+	if p.Price <= 0 {
+		return fmt.Errorf("price must be greater than 0")
+	}
+	if p.Stock < 0 {
+		return fmt.Errorf("stock must not be negative")
+	}
+	return nil
 }
 
 func (p *ProductHandler) CreateProduct(w http.ResponseWriter, r *http.Request) {
@@ -235,9 +262,145 @@ func (p *ProductHandler) CreateProduct(w http.ResponseWriter, r *http.Request) {
 
 }
 
-func (p *ProductHandler) GetProductMulti(r *http.Request, idList []string) []ProductResponse {
+func (p *ProductHandler) ImportProduct(w http.ResponseWriter, r *http.Request) {
+	r.ParseMultipartForm(32 << 20)
+	logger := mlog.L(r.Context())
+	file, _, err := r.FormFile("file")
+	if err != nil {
+		logger.Error("error parsing the file.", "error", err)
+		p.ResponseJson(w, map[string]string{"message": "Error parsing the file"}, http.StatusBadRequest)
+		return
+	}
+	defer file.Close()
+
+	rows, err := xlsx.Read[TCreateProduct](file, xlsx.ReadOptions{Sheet: r.FormValue("sheet")})
+	if err != nil {
+		logger.Error("error reading the workbook.", "error", err)
+		p.ResponseJson(w, map[string]string{"message": err.Error()}, http.StatusBadRequest)
+		return
+	}
+
+	dryRun, _ := strconv.ParseBool(r.FormValue("dryRun"))
+	results := p.ImportProducts(r, rows, dryRun)
+	logger.Info("Products imported.", "total", len(results), "dryRun", dryRun)
+
+	p.WriteExport(w, r, xlsx.NewXlsx(results, xlsx.XlsxOptions{Sheet: "Result"}), "import-result")
+}
+
+// ProductSnapshots are the two product lists compared by DiffExport
+type ProductSnapshots struct {
+	Before []ProductResponse `json:"before"`
+	After  []ProductResponse `json:"after"`
+}
+
+// DiffExport compares two product snapshots, either uploaded as the
+// "before" and "after" workbooks of a multipart form or posted as JSON, and
+// responds with the Added, Removed and Changed report.
+func (p *ProductHandler) DiffExport(w http.ResponseWriter, r *http.Request) {
+	logger := mlog.L(r.Context())
+
+	opt := xlsx.DiffOptions{Key: "ID"}
+	var (
+		report *xlsx.Xlsx
+		err    error
+	)
+	if strings.HasPrefix(r.Header.Get(httpService.ContentType), "multipart/form-data") {
+		r.ParseMultipartForm(32 << 20)
+		if key := r.FormValue("key"); key != "" {
+			opt.Key = key
+		}
+		opt.Sheet = r.FormValue("sheet")
+
+		before, _, errBefore := r.FormFile("before")
+		after, _, errAfter := r.FormFile("after")
+		if errBefore != nil || errAfter != nil {
+			logger.Error("error parsing the files.", "before", errBefore, "after", errAfter)
+			p.ResponseJson(w, map[string]string{"message": "Both before and after files are required"}, http.StatusBadRequest)
+			return
+		}
+		defer before.Close()
+		defer after.Close()
+
+		report, err = xlsx.DiffWorkbooks(before, after, opt)
+	} else {
+		var snapshots ProductSnapshots
+		if err := json.NewDecoder(r.Body).Decode(&snapshots); err != nil {
+			logger.Error("error decoding the snapshots.", "error", err)
+			p.ResponseJson(w, map[string]string{"message": "Invalid request body"}, http.StatusBadRequest)
+			return
+		}
+		report, err = xlsx.Diff(snapshots.Before, snapshots.After, opt)
+	}
+	if err != nil {
+		logger.Error("error comparing the snapshots.", "error", err)
+		p.ResponseJson(w, map[string]string{"message": err.Error()}, http.StatusBadRequest)
+		return
+	}
+
+	p.WriteExport(w, r, report, "products-diff")
+}
+
+// ImportProducts creates every valid row in the catalog service and returns
+// one result per input line. With dryRun the rows are only validated.
+func (p *ProductHandler) ImportProducts(r *http.Request, rows []xlsx.Row[TCreateProduct], dryRun bool) []Data {
 	l := mlog.L(r.Context())
 
+	const poolSize = 100 // Number of concurrent workers
+
+	var wg sync.WaitGroup
+	semaphore := make(chan struct{}, poolSize)
+	results := make([]Data, len(rows))
+
+	for i, row := range rows {
+		results[i] = Data{NO: row.Line, Name: row.Value.Name}
+
+		if !row.Valid() {
+			messages := make([]string, len(row.Errors))
+			for j, err := range row.Errors {
+				messages[j] = err.Error()
+			}
+			results[i].Status = StatusFailed
+			results[i].Message = strings.Join(messages, "; ")
+			continue
+		}
+
+		if dryRun {
+			results[i].Status = StatusValid
+			continue
+		}
+
+		wg.Add(1)
+		go func(result *Data, payload TCreateProduct) {
+			defer wg.Done()
+			semaphore <- struct{}{} // Limit concurrency
+			defer func() { <-semaphore }()
+
+			apiCreate := httpService.HttpPostClient[CreateProductResponse]("http://localhost:8000/api/product", payload, httpService.Options{})
+			if apiCreate.StatusCode < 200 || apiCreate.StatusCode >= 300 || apiCreate.Data == nil || !apiCreate.Data.Success {
+				l.Error("Error creating product", "line", result.NO, "response", apiCreate)
+				result.Status = StatusFailed
+				result.Message = apiCreate.Message
+				if apiCreate.Description != "" {
+					result.Message = apiCreate.Description
+				}
+				return
+			}
+
+			result.Status = StatusSuccess
+			result.Message = apiCreate.Data.Data.ID
+		}(&results[i], row.Value)
+	}
+
+	wg.Wait()
+	return results
+}
+
+// GetProductMulti fetches the products of idList, counting every fetch as a
+// step of progress, which may be nil
+func (p *ProductHandler) GetProductMulti(ctx context.Context, idList []string, progress *jobs.Progress) []ProductResponse {
+	l := mlog.L(ctx)
+	progress.SetTotal(len(idList))
+
 	const poolSize = 100   // Number of concurrent workers
 	const batchSize = 1000 // Process requests in batches to avoid overwhelming system resources
 
@@ -254,6 +417,7 @@ func (p *ProductHandler) GetProductMulti(r *http.Request, idList []string) []Pro
 				defer wg.Done()
 				semaphore <- struct{}{} // Limit concurrency
 				defer func() { <-semaphore }()
+				defer progress.Step(1)
 
 				// HTTP request to fetch product data
 				product, err := httpService.HttpGetClient[TProductResponse](&httpService.Options{
@@ -309,15 +473,54 @@ func main() {
 
 	idList := loadProducts()
 
+	storageOptions, err := exportStorageOptions()
+	if err != nil {
+		log.Fatal(err)
+	}
+	exportStorage, err := newExportStorage(storageOptions)
+	if err != nil {
+		log.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go storage.RunCleanup(ctx, exportStorage, exportCleanupInterval, logger)
+
+	exportJobs, err := jobs.NewManager(jobs.Options{
+		Workers:   exportWorkers,
+		QueueSize: exportQueueSize,
+		Storage:   exportStorage,
+		Retention: storageOptions.TTL,
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer exportJobs.Close()
+
 	r := http.NewServeMux()
-	h := &ProductHandler{}
+	h := &ProductHandler{jobs: exportJobs, storage: exportStorage}
 
 	r.HandleFunc("POST /upload", h.UploadFile)
 	r.HandleFunc("POST /product", h.CreateProduct)
+	r.HandleFunc("POST /product/import", h.ImportProduct)
+	r.HandleFunc("POST /exports/diff", h.DiffExport)
+	r.HandleFunc("POST /exports", func(w http.ResponseWriter, r *http.Request) {
+		h.CreateExport(w, r, idList)
+	})
+	r.HandleFunc("GET /exports/{id}", h.ExportStatus)
+	r.HandleFunc("GET /exports/{id}/file", h.ExportFile)
 	r.HandleFunc("GET /product", func(w http.ResponseWriter, r *http.Request) {
 		start := time.Now().UnixMilli()
-		products := h.GetProductMulti(r, idList)
-		h.ResponseProducts(w, products, start)
+		products := h.GetProductMulti(r.Context(), idList, nil)
+		failed := missingProducts(idList, products)
+		format, ok := xlsx.Negotiate(r.URL.Query().Get("format"), r.Header.Get("Accept"))
+		if !ok && h.UnsupportedFormat(w, r) {
+			return
+		}
+		if ok {
+			h.ResponseExport(w, r, format, products, failed)
+			return
+		}
+		h.ResponseProducts(w, r, products, failed, start)
 	})
 
 	r.HandleFunc("GET /products", func(w http.ResponseWriter, r *http.Request) {
@@ -351,6 +554,25 @@ func main() {
 		// }
 
 		// h.ResponseProducts(w, products, start)
+		format, ok := xlsx.Negotiate(r.URL.Query().Get("format"), r.Header.Get("Accept"))
+		if !ok && h.UnsupportedFormat(w, r) {
+			return
+		}
+		if ok {
+			products := make([]DataProducts, 0, len(apiResponse))
+			for _, v := range apiResponse {
+				products = append(products, v.Data)
+			}
+			exporter, err := xlsx.NewExporter(format, products, xlsx.XlsxOptions{Sheet: "Products"})
+			if err != nil {
+				mlog.L(r.Context()).Error("error creating the export.", "error", err)
+				h.ResponseJson(w, map[string]string{"message": err.Error()}, http.StatusInternalServerError)
+				return
+			}
+			h.WriteExport(w, r, exporter, "products")
+			return
+		}
+
 		logger.Info("Received product", "product", apiResponse)
 		fmt.Println(fmt.Sprintf("%.2f ms", float64(time.Now().UnixMilli()-start)/1000))
 		h.ResponseJson(w, apiResponse, http.StatusOK)
@@ -359,7 +581,7 @@ func main() {
 	StartHttp(r, logger)
 }
 
-func (p *ProductHandler) ResponseProducts(w http.ResponseWriter, products []ProductResponse, start int64) {
+func (p *ProductHandler) ResponseProducts(w http.ResponseWriter, r *http.Request, products []ProductResponse, failed []FailedProduct, start int64) {
 	response := map[string]any{
 		"durations": fmt.Sprintf("%.2f ms", float64(time.Now().UnixMilli()-start)/1000),
 		"products":  products,
@@ -367,7 +589,7 @@ func (p *ProductHandler) ResponseProducts(w http.ResponseWriter, products []Prod
 		"total":     len(products),
 	}
 
-	SaveExcelFile(products)
+	SaveExcelFile(r.Context(), p.storage, products, failed)
 	p.ResponseJson(w, response, http.StatusOK)
 }
 
@@ -398,24 +620,560 @@ func StartHttp(r *http.ServeMux, logger *slog.Logger) {
 	logger.Info("server exiting")
 }
 
-func SaveExcelFile(products []ProductResponse) {
+// streamRowThreshold is the number of products above which the export is
+// written through the streaming writer instead of an in-memory workbook.
+const streamRowThreshold = 10000
+
+// lowStockThreshold is the stock level below which a product is listed on
+// the "Low Stock" sheet.
+const lowStockThreshold = 10
+
+// exportWorkers is the number of exports generated in the background at the
+// same time, and exportQueueSize the number that may wait for a worker.
+const (
+	exportWorkers   = 2
+	exportQueueSize = 100
+)
+
+// Exported files are kept for exportTTL within exportQuota bytes unless
+// EXPORT_TTL or EXPORT_QUOTA say otherwise, and checked every
+// exportCleanupInterval.
+const (
+	exportTTL             = 24 * time.Hour
+	exportQuota           = 1 << 30
+	exportCleanupInterval = 10 * time.Minute
+)
+
+// exportStorageOptions reads the retention of export files
+func exportStorageOptions() (storage.Options, error) {
+	opt := storage.Options{TTL: exportTTL, Quota: exportQuota}
+	if ttl := os.Getenv("EXPORT_TTL"); ttl != "" {
+		d, err := time.ParseDuration(ttl)
+		if err != nil {
+			return opt, fmt.Errorf("invalid EXPORT_TTL: %v", err)
+		}
+		opt.TTL = d
+	}
+	if quota := os.Getenv("EXPORT_QUOTA"); quota != "" {
+		n, err := strconv.ParseInt(quota, 10, 64)
+		if err != nil {
+			return opt, fmt.Errorf("invalid EXPORT_QUOTA: %v", err)
+		}
+		opt.Quota = n
+	}
+	return opt, nil
+}
+
+// newExportStorage creates the storage of export files chosen by
+// EXPORT_STORAGE: "local" (default) in EXPORT_DIR, "memory" or
+// "fileservice".
+func newExportStorage(opt storage.Options) (storage.Storage, error) {
+
+	switch kind := os.Getenv("EXPORT_STORAGE"); kind {
+	case "", "local":
+		dir := os.Getenv("EXPORT_DIR")
+		if dir == "" {
+			dir = filepath.Join(os.TempDir(), "exports")
+		}
+		return storage.NewLocal(dir, opt)
+	case "memory":
+		return storage.NewMemory(opt), nil
+	case "fileservice":
+		fs := storage.NewFileService("http://localhost:8001", "exports", opt)
+		// Picks up the exports of previous runs for cleanup
+		if err := fs.Sync(); err != nil {
+			return nil, err
+		}
+		return fs, nil
+	default:
+		return nil, fmt.Errorf("unknown EXPORT_STORAGE %q", kind)
+	}
+}
+
+// exportLanguages are the languages product exports are translated to
+var exportLanguages = language.NewMatcher([]language.Tag{language.English, language.Thai})
+
+// productCatalog translates the labels of the product export
+var productCatalog = xlsx.Catalog{
+	"th": {
+		"ID":              "รหัสสินค้า",
+		"Link":            "ลิงก์",
+		"Product Name":    "ชื่อสินค้า",
+		"Description":     "รายละเอียด",
+		"Unit Price":      "ราคาต่อหน่วย",
+		"Image":           "รูปภาพ",
+		"Stock":           "คงเหลือ",
+		"Inventory Value": "มูลค่าสินค้าคงคลัง",
+		"Total":           "รวม",
+		"Average":         "เฉลี่ย",
+		"Count":           "จำนวน",
+	},
+}
+
+type FailedProduct struct {
+	ID string `json:"id" xlsx:"ID;width=38"`
+}
+
+// missingProducts returns the ids that could not be fetched
+func missingProducts(idList []string, products []ProductResponse) []FailedProduct {
+	fetched := make(map[string]bool, len(products))
+	for _, product := range products {
+		fetched[product.ID] = true
+	}
+
+	failed := []FailedProduct{}
+	for _, id := range idList {
+		if !fetched[id] {
+			failed = append(failed, FailedProduct{ID: id})
+		}
+	}
+	return failed
+}
+
+// Workbook is implemented by both the in-memory and the streaming writer
+type Workbook interface {
+	xlsx.Exporter
+	SaveExcelFile() error
+}
+
+// ProductExport describes one export of the product list
+type ProductExport struct {
+	Products []ProductResponse
+	Failed   []FailedProduct
+	FileName string
+	// Images embeds product thumbnails fetched from the file-service
+	Images bool
+	// Dashboard adds chart and pivot sheets for the weekly report
+	Dashboard bool
+	// Password encrypts the workbook
+	Password string
+	// StockOnly locks every column but Stock, for files sent to suppliers
+	StockOnly bool
+	// Locale translates the export, "th" also shows Buddhist-era dates
+	Locale string
+	// About adds a sheet with the provenance of the export. SessionID and
+	// Filters identify the request it was made for.
+	About     bool
+	SessionID string
+	Filters   url.Values
+	// Logger is the logger of the request, slog.Default() when nil
+	Logger *slog.Logger
+	// MaxRows rolls the products over to "Products (2)" and so on past
+	// this many rows per sheet. With Zip each part is a workbook of its
+	// own of at most streamRowThreshold rows, bundled without the Low
+	// Stock and Failed IDs sheets.
+	MaxRows int
+	Zip     bool
+}
+
+// productSources are the catalog endpoints product exports are built from
+var productSources = []string{
+	"http://localhost:8000/api/product",
+	"http://localhost:8000/api/product/{id}",
+}
+
+// about describes the provenance of a product export
+func (e ProductExport) about() *xlsx.About {
+	about := &xlsx.About{
+		Title:       "Product export",
+		Author:      os.Getenv("SERVICE_NAME"),
+		SessionID:   e.SessionID,
+		Filters:     e.Filters,
+		Sources:     productSources,
+		Counts:      map[string]int{"Products": len(e.Products), "Failed": len(e.Failed)},
+		GeneratedAt: time.Now(),
+	}
+	if e.About {
+		about.Sheet = "About"
+	}
+	for _, failed := range e.Failed {
+		about.Failures = append(about.Failures, failed.ID)
+	}
+	return about
+}
+
+// productOptions are the xlsx options of the Products sheet
+func productOptions(e ProductExport) xlsx.XlsxOptions {
 	options := xlsx.XlsxOptions{
-		FileName: "Book1.xlsx",
-		Headers:  []string{"ID", "Name", "Description", "Price", "Image", "Stock"},
+		FileName: e.FileName,
+		Sheet:    "Products",
+		Password: e.Password,
+		About:    e.about(),
+		MaxRows:  e.MaxRows,
+		AutoFit:  true,
+		Table:    &xlsx.TableOptions{Style: "TableStyleMedium2"},
+		// Keep the header and the product ID and name in view
+		FreezeHeader:  true,
+		FreezeColumns: 2,
+		ConditionalFormats: []xlsx.ConditionalFormat{
+			{Column: "Stock", Operator: "<", Value: lowStockThreshold, Fill: "FFC7CE", FontColor: "9C0006"},
+		},
+		Validations: []xlsx.Validation{
+			{Column: "Price", Operator: ">", Value: 0, Message: "Price must be greater than 0"},
+			{Column: "Stock", Operator: ">=", Value: 0, Message: "Stock must not be negative"},
+		},
+		Formulas: []xlsx.Formula{
+			{Header: "Inventory Value", Expr: "{Price}*{Stock}", Width: 18, NumFmt: "#,##0.00"},
+		},
+		Summaries: []xlsx.Summary{
+			{Label: productCatalog.Text(e.Locale, "Total"), Func: "SUM", Columns: []string{"Stock", "Inventory Value"}},
+			{Label: productCatalog.Text(e.Locale, "Average"), Func: "AVERAGE", Columns: []string{"Price"}},
+			{Label: productCatalog.Text(e.Locale, "Count"), Func: "COUNTA", Columns: []string{"Product Name"}},
+		},
+		Locale:      e.Locale,
+		Catalog:     productCatalog,
+		BuddhistEra: strings.HasPrefix(e.Locale, "th"),
+	}
+	if e.Images {
+		options.ImageFetcher = fetchProductImage
+	}
+	if e.StockOnly {
+		options.Protection = &xlsx.Protection{
+			Password:    os.Getenv("EXPORT_EDIT_PASSWORD"),
+			Editable:    []string{"Stock"},
+			AllowSort:   true,
+			AllowFilter: true,
+		}
+	}
+	if e.Dashboard {
+		options.Charts = []xlsx.Chart{
+			{Sheet: "Stock Chart", Title: "Stock per Product", Category: "Product Name", Values: []string{"Stock"}},
+			{Sheet: "Price Chart", Title: "Price per Product", Type: "line", Category: "Product Name", Values: []string{"Price"}},
+		}
+		options.PivotTables = []xlsx.PivotTable{{
+			Sheet: "Price Pivot",
+			Rows:  []string{"Price"},
+			Data:  []xlsx.PivotValue{{Column: "Product Name", Func: "Count", Name: "Products"}, {Column: "Stock", Name: "Total Stock"}},
+		}}
+	}
+	return options
+}
+
+// NewProductWorkbook builds the product export, streaming it when the
+// dataset is too large to hold in memory
+func NewProductWorkbook(e ProductExport) (Workbook, error) {
+	options := productOptions(e)
+	lowStock := []ProductResponse{}
+	for _, product := range e.Products {
+		if product.Stock < lowStockThreshold {
+			lowStock = append(lowStock, product)
+		}
 	}
 
-	if len(products) > 0 {
-		f := xlsx.NewXlsx(products, options)
-		if err := f.SaveExcelFile(); err != nil {
-			fmt.Println("Error saving Excel file:", err)
+	lowStockOptions := options
+	lowStockOptions.Sheet = "Low Stock"
+	lowStockOptions.Charts, lowStockOptions.PivotTables = nil, nil
+	failedOptions := xlsx.XlsxOptions{Sheet: "Failed IDs", Locale: e.Locale, Catalog: productCatalog}
+
+	if len(e.Products) > streamRowThreshold {
+		// Charts and pivot tables need the data in memory
+		if len(options.Charts) > 0 || len(options.PivotTables) > 0 {
+			logger := e.Logger
+			if logger == nil {
+				logger = slog.Default()
+			}
+			logger.Warn("dashboard left out of a streamed export.", "products", len(e.Products), "threshold", streamRowThreshold)
+			options.Charts, options.PivotTables = nil, nil
+		}
+		sw, err := xlsx.NewStreamWriter[ProductResponse](options)
+		if err != nil {
+			return nil, err
+		}
+		if err := sw.WriteRows(e.Products); err != nil {
+			sw.Close()
+			return nil, err
 		}
+		xlsx.AddStreamSheet(sw, lowStock, lowStockOptions)
+		xlsx.AddStreamSheet(sw, e.Failed, failedOptions)
+		return sw, nil
+	}
+
+	// A branded report template replaces the generated Products sheet
+	if template := os.Getenv("PRODUCT_REPORT_TEMPLATE"); template != "" {
+		options.Template = template
+		options.TemplateData = map[string]any{
+			"GeneratedAt": time.Now().Format("2006-01-02 15:04:05"),
+			"Total":       len(e.Products),
+			"LowStock":    len(lowStock),
+			"Failed":      len(e.Failed),
+		}
+	}
+
+	f := xlsx.NewXlsx(e.Products, options)
+	xlsx.AddSheet(f, lowStock, lowStockOptions)
+	xlsx.AddSheet(f, e.Failed, failedOptions)
+	return f, nil
+}
+
+// fetchProductImage downloads a product image from the file-service
+func fetchProductImage(url string) ([]byte, error) {
+	apiResponse, err := httpService.HttpGetFile(&httpService.Options{
+		URL:     strings.Replace(url, "{BASE_URL}", "http://localhost:8001", 1),
+		Timeout: 10,
+	})
+	return apiResponse.Data, err
+}
+
+// SaveExcelFile saves a copy of the products to store under a name of its
+// own for every request, so concurrent requests do not overwrite each other
+// and the copies expire with the other exports
+func SaveExcelFile(ctx context.Context, store storage.Storage, products []ProductResponse, failed []FailedProduct) {
+	if len(products) == 0 {
+		return
+	}
+
+	session := mlog.SessionID(ctx)
+	name := savedFileName(session, time.Now())
+	f, err := NewProductWorkbook(ProductExport{
+		Products:  products,
+		Failed:    failed,
+		FileName:  name,
+		SessionID: session,
+		Logger:    mlog.L(ctx),
+	})
+	if err != nil {
+		fmt.Println("Error creating Excel file:", err)
+		return
+	}
+
+	pr, pw := io.Pipe()
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				pw.CloseWithError(fmt.Errorf("export panicked: %v", r))
+			}
+		}()
+		pw.CloseWithError(f.Write(pw))
+	}()
+	_, err = store.Put(name, pr)
+	pr.CloseWithError(err)
+	if err != nil {
+		fmt.Println("Error saving Excel file:", err)
+	}
+}
+
+// savedFileName names the copy saved for a request, e.g.
+// products-<session>-20240914-101500.123.xlsx. The session comes from a
+// request header, so only characters safe in a file name are kept.
+func savedFileName(session string, at time.Time) string {
+	session = strings.Map(func(r rune) rune {
+		if r == '-' || r == '_' || ('0' <= r && r <= '9') || ('a' <= r && r <= 'z') || ('A' <= r && r <= 'Z') {
+			return r
+		}
+		return -1
+	}, session)
+	if session == "" {
+		session = "local"
+	}
+	return fmt.Sprintf("products-%s-%s.xlsx", session, at.Format("20060102-150405.000"))
+}
+
+// NewProductExporter builds the product export in the negotiated format.
+// Only the xlsx format carries the extra Low Stock and Failed IDs sheets.
+func NewProductExporter(format xlsx.Format, e ProductExport) (xlsx.Exporter, error) {
+	if format == xlsx.FormatXLSX && e.Zip {
+		// Every part is built in memory, so none may be larger than what
+		// NewProductWorkbook would stream
+		options := productOptions(e)
+		if options.MaxRows <= 0 || options.MaxRows > streamRowThreshold {
+			options.MaxRows = streamRowThreshold
+		}
+		return xlsx.NewZip(e.Products, options), nil
+	}
+	if format == xlsx.FormatXLSX {
+		return NewProductWorkbook(e)
+	}
+	return xlsx.NewExporter(format, e.Products, xlsx.XlsxOptions{FileName: e.FileName, Locale: e.Locale, Catalog: productCatalog})
+}
+
+// ResponseExport streams the product export back as an attachment
+func (p *ProductHandler) ResponseExport(w http.ResponseWriter, r *http.Request, format xlsx.Format, products []ProductResponse, failed []FailedProduct) {
+	logger := mlog.L(r.Context())
+
+	name := exportName()
+	e := exportRequest(r)
+	e.Products, e.Failed, e.FileName = products, failed, name+"."+string(format)
+	exporter, err := NewProductExporter(format, e)
+	if err != nil {
+		logger.Error("error creating the export.", "error", err)
+		p.ResponseJson(w, map[string]string{"message": err.Error()}, http.StatusInternalServerError)
+		return
+	}
+	p.WriteExport(w, r, exporter, name)
+}
+
+// exportRequest reads the export settings of a request
+func exportRequest(r *http.Request) ProductExport {
+	images, _ := strconv.ParseBool(r.URL.Query().Get("images"))
+	dashboard, _ := strconv.ParseBool(r.URL.Query().Get("dashboard"))
+	stockOnly, _ := strconv.ParseBool(r.URL.Query().Get("stockOnly"))
+	about, _ := strconv.ParseBool(r.URL.Query().Get("about"))
+	maxRows, _ := strconv.Atoi(r.URL.Query().Get("maxRows"))
+	zip, _ := strconv.ParseBool(r.URL.Query().Get("zip"))
+	return ProductExport{
+		Images:    images,
+		Dashboard: dashboard,
+		// Kept out of the URL so it does not end up in access logs
+		Password:  r.Header.Get("X-Export-Password"),
+		StockOnly: stockOnly,
+		Locale:    exportLocale(r),
+		About:     about,
+		SessionID: mlog.SessionID(r.Context()),
+		Filters:   r.URL.Query(),
+		Logger:    mlog.L(r.Context()),
+		MaxRows:   maxRows,
+		Zip:       zip,
+	}
+}
+
+func exportName() string {
+	return fmt.Sprintf("products-%s", time.Now().Format("20060102-150405"))
+}
+
+// CreateExport queues the product export in the format of the format query
+// parameter, xlsx by default, and responds with its job. The export takes
+// the same parameters as GET /product.
+func (p *ProductHandler) CreateExport(w http.ResponseWriter, r *http.Request, idList []string) {
+	logger := mlog.L(r.Context())
+
+	format := xlsx.FormatXLSX
+	if name := r.URL.Query().Get("format"); name != "" {
+		var ok bool
+		if format, ok = xlsx.Negotiate(name, ""); !ok {
+			p.ResponseJson(w, map[string]string{"message": "Unsupported format " + name}, http.StatusBadRequest)
+			return
+		}
+	}
+
+	e := exportRequest(r)
+	job, err := p.jobs.Submit(r.Context(), func(ctx context.Context, progress *jobs.Progress) (jobs.Result, error) {
+		products := p.GetProductMulti(ctx, idList, progress)
+		name := exportName()
+		e.Products, e.Failed, e.FileName = products, missingProducts(idList, products), name+"."+string(format)
+		exporter, err := NewProductExporter(format, e)
+		if err != nil {
+			return jobs.Result{}, err
+		}
+		return jobs.Result{Exporter: exporter, Name: name, Rows: len(e.Products), Failed: len(e.Failed)}, nil
+	})
+	if err != nil {
+		logger.Error("error queueing the export.", "error", err)
+		p.ResponseJson(w, map[string]string{"message": err.Error()}, http.StatusServiceUnavailable)
+		return
+	}
+
+	logger.Info("Export queued.", "job", job.ID, "format", format)
+	w.Header().Set("Location", "/exports/"+job.ID)
+	p.ResponseJson(w, job, http.StatusAccepted)
+}
+
+// ExportStatus responds with the progress of an export job
+func (p *ProductHandler) ExportStatus(w http.ResponseWriter, r *http.Request) {
+	job, err := p.jobs.Get(r.PathValue("id"))
+	if err != nil {
+		p.ResponseJson(w, map[string]string{"message": err.Error()}, http.StatusNotFound)
+		return
+	}
+	p.ResponseJson(w, job, http.StatusOK)
+}
+
+// ExportFile downloads the file of a finished export job
+func (p *ProductHandler) ExportFile(w http.ResponseWriter, r *http.Request) {
+	file, job, contentType, err := p.jobs.Open(r.PathValue("id"))
+	switch {
+	case errors.Is(err, jobs.ErrNotFound):
+		p.ResponseJson(w, map[string]string{"message": err.Error()}, http.StatusNotFound)
+		return
+	case errors.Is(err, jobs.ErrNotReady):
+		p.ResponseJson(w, job, http.StatusConflict)
+		return
+	case errors.Is(err, jobs.ErrFailed):
+		p.ResponseJson(w, job, http.StatusInternalServerError)
+		return
+	case errors.Is(err, jobs.ErrExpired):
+		p.ResponseJson(w, map[string]string{"message": err.Error()}, http.StatusGone)
+		return
+	case err != nil:
+		mlog.L(r.Context()).Error("error opening the export.", "error", err)
+		p.ResponseJson(w, map[string]string{"message": err.Error()}, http.StatusInternalServerError)
+		return
+	}
+	defer file.Close()
+
+	w.Header().Set(httpService.ContentType, contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, job.FileName))
+	w.Header().Set("Content-Length", strconv.FormatInt(job.Size, 10))
+	if _, err := io.Copy(w, file); err != nil {
+		mlog.L(r.Context()).Error("error writing the export.", "error", err)
+	}
+}
+
+// exportLocale picks the export language from the lang query parameter or
+// the Accept-Language header. It is empty when neither is given, which keeps
+// the untranslated export.
+func exportLocale(r *http.Request) string {
+	lang, accept := r.URL.Query().Get("lang"), r.Header.Get("Accept-Language")
+	if lang == "" && accept == "" {
+		return ""
+	}
+	tag, _ := language.MatchStrings(exportLanguages, lang, accept)
+	base, _ := tag.Base()
+	return base.String()
+}
+
+// UnsupportedFormat responds with 400 when the format query parameter asks
+// for a format there is no exporter for, and reports whether it did
+func (p *ProductHandler) UnsupportedFormat(w http.ResponseWriter, r *http.Request) bool {
+	name := r.URL.Query().Get("format")
+	if name == "" {
+		return false
+	}
+	p.ResponseJson(w, map[string]string{"message": "Unsupported format " + name}, http.StatusBadRequest)
+	return true
+}
+
+// WriteExport writes the exporter output as a download named after name.
+// The export is rendered to a temporary file first, so a failure is answered
+// with 500 instead of a truncated attachment.
+func (p *ProductHandler) WriteExport(w http.ResponseWriter, r *http.Request, exporter xlsx.Exporter, name string) {
+	logger := mlog.L(r.Context())
+
+	tmp, err := os.CreateTemp("", "export-*")
+	if err != nil {
+		logger.Error("error creating the export file.", "error", err)
+		p.ResponseJson(w, map[string]string{"message": err.Error()}, http.StatusInternalServerError)
+		return
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	if err := exporter.Write(tmp); err != nil {
+		logger.Error("error writing the export.", "error", err)
+		p.ResponseJson(w, map[string]string{"message": err.Error()}, http.StatusInternalServerError)
+		return
+	}
+	size, err := tmp.Seek(0, io.SeekCurrent)
+	if err == nil {
+		_, err = tmp.Seek(0, io.SeekStart)
+	}
+	if err != nil {
+		logger.Error("error reading the export.", "error", err)
+		p.ResponseJson(w, map[string]string{"message": err.Error()}, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set(httpService.ContentType, exporter.ContentType())
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.%s"`, name, exporter.Extension()))
+	w.Header().Set("Content-Length", strconv.FormatInt(size, 10))
+	if _, err := io.Copy(w, tmp); err != nil {
+		logger.Error("error sending the export.", "error", err)
 	}
 }
 
 func AsyncHTTP[T any](users []string) ([]T, error) {
 	// TProductResponse
 	var wg sync.WaitGroup
-	results := make([]T, len(users))
+	results := make([]T, 0, len(users))
 	errors := make(chan error, len(users))
 	resultChan := make(chan T, len(users))
 	// Worker Pool Size
@@ -26,8 +26,14 @@ func L(ctx context.Context) *slog.Logger {
 	}
 }
 
+// SessionID returns the session of the request, as logged with every line
+func SessionID(ctx context.Context) string {
+	session, _ := ctx.Value(sessionKey).(string)
+	return session
+}
+
 func logMiddleware(ctx context.Context, logger *slog.Logger) *slog.Logger {
-	session, exits := ctx.Value(loggerKey).(string)
+	session, exits := ctx.Value(sessionKey).(string)
 	if !exits {
 		session = uuid.New().String()
 	}
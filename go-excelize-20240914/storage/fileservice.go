@@ -0,0 +1,210 @@
+package storage
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	httpService "github.com/sing3demons/20240914/excelize/http-service"
+)
+
+// FileService uploads files to the file-service. The files it knows are
+// those in its folder when Sync last ran and the ones it uploaded since.
+type FileService struct {
+	// baseURL is the file-service address, e.g. http://localhost:8001
+	baseURL string
+	// folder is the sub folder of the files on the file-service
+	folder string
+	opt    Options
+
+	mu    sync.RWMutex
+	files map[string]Object
+}
+
+// fileServiceResponse is the body of the file-service API. Its statusCode
+// carries the outcome, the HTTP status of most routes is 200 regardless.
+type fileServiceResponse[T any] struct {
+	StatusCode int    `json:"statusCode"`
+	Message    string `json:"message"`
+	Data       T      `json:"data"`
+}
+
+// fileServiceFile is an entry of GET /api/files
+type fileServiceFile struct {
+	Path    string  `json:"path"`
+	Size    int64   `json:"size"`
+	MtimeMs float64 `json:"mtimeMs"`
+}
+
+// fileServiceRoot is the directory the file-service keeps its files in
+const fileServiceRoot = "public/images"
+
+// errMissingDownload is the message of /api/download for a file that does
+// not exist, sent with a 400
+const errMissingDownload = "file already exists"
+
+func NewFileService(baseURL, folder string, opt Options) *FileService {
+	return &FileService{
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		folder:  folder,
+		opt:     opt,
+		files:   map[string]Object{},
+	}
+}
+
+// failed returns the message of a file-service response that is not a
+// success
+func failed[T any](apiResponse httpService.HttpResponse[*fileServiceResponse[T]]) (string, bool) {
+	if apiResponse.StatusCode < 200 || apiResponse.StatusCode >= 300 || apiResponse.Data == nil {
+		return strings.TrimSpace(apiResponse.Message + " " + apiResponse.Description), true
+	}
+	if code := apiResponse.Data.StatusCode; code != 0 && (code < 200 || code >= 300) {
+		return apiResponse.Data.Message, true
+	}
+	return "", false
+}
+
+// Sync replaces the known files with those in the folder on the
+// file-service, so files uploaded before a restart are cleaned up too
+func (s *FileService) Sync() error {
+	apiResponse, err := httpService.HttpGetClient[fileServiceResponse[[]fileServiceFile]](&httpService.Options{
+		URL: s.baseURL + "/api/files",
+	})
+	if err != nil {
+		return fmt.Errorf("failed to list files: %v", err)
+	}
+	if message, ok := failed(apiResponse); ok {
+		return fmt.Errorf("failed to list files: %s", message)
+	}
+
+	dir := path.Join(fileServiceRoot, s.folder)
+	files := map[string]Object{}
+	for _, f := range apiResponse.Data.Data {
+		if path.Dir(filepath.ToSlash(f.Path)) != dir {
+			continue
+		}
+		key := path.Base(filepath.ToSlash(f.Path))
+		files[key] = Object{Key: key, Size: f.Size, CreatedAt: time.UnixMilli(int64(f.MtimeMs))}
+	}
+
+	s.mu.Lock()
+	s.files = files
+	s.mu.Unlock()
+	return nil
+}
+
+// countingReader counts the bytes read through it
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+func (s *FileService) Put(key string, r io.Reader) (Object, error) {
+	body := &countingReader{r: r}
+
+	// The file-service keeps the extension of the uploaded file name
+	apiResponse := httpService.HttpPostForm[fileServiceResponse[any]](httpService.OptionPostForm{
+		URL:     s.baseURL + "/api/upload",
+		Timeout: 300,
+		FormFiles: []httpService.FormFile{
+			{
+				File:       body,
+				FileHeader: &multipart.FileHeader{Filename: key},
+			},
+		},
+		Fields: httpService.FormFields{
+			"replaceFileName": strings.TrimSuffix(key, path.Ext(key)),
+			"filePath":        s.folder,
+		},
+	})
+	if message, ok := failed(apiResponse); ok {
+		return Object{}, fmt.Errorf("failed to upload %s: %s", key, message)
+	}
+
+	o := Object{Key: key, Size: body.n, CreatedAt: time.Now()}
+	s.mu.Lock()
+	s.files[key] = o
+	s.mu.Unlock()
+	return afterPut(s, s.opt, o)
+}
+
+func (s *FileService) Open(key string) (io.ReadCloser, Object, error) {
+	s.mu.RLock()
+	o, ok := s.files[key]
+	s.mu.RUnlock()
+	if !ok {
+		return nil, Object{}, ErrNotFound
+	}
+
+	query := url.Values{"filePath": {s.folder}, "filename": {key}}
+	apiResponse, err := httpService.HttpGetFile(&httpService.Options{
+		URL:     s.baseURL + "/api/download?" + query.Encode(),
+		Timeout: 60,
+	})
+	// The file was removed on the file-service itself
+	if apiResponse.StatusCode == http.StatusNotFound ||
+		(apiResponse.StatusCode == http.StatusBadRequest && strings.Contains(apiResponse.Description, errMissingDownload)) {
+		s.mu.Lock()
+		delete(s.files, key)
+		s.mu.Unlock()
+		return nil, Object{}, ErrNotFound
+	}
+	if err != nil {
+		return nil, Object{}, fmt.Errorf("failed to download %s: %v", key, err)
+	}
+	return io.NopCloser(bytes.NewReader(apiResponse.Data)), o, nil
+}
+
+// Delete removes the file from the disk of the file-service, not only from
+// its database
+func (s *FileService) Delete(key string) error {
+	s.mu.RLock()
+	_, ok := s.files[key]
+	s.mu.RUnlock()
+	if !ok {
+		return ErrNotFound
+	}
+
+	apiResponse, err := httpService.HttpDeleteClient[fileServiceResponse[any]](&httpService.Options{
+		URL:   s.baseURL + "/api/file/" + url.PathEscape(key),
+		Param: url.Values{"filePath": {s.folder}, "purge": {"true"}},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete %s: %v", key, err)
+	}
+	if message, ok := failed(apiResponse); ok {
+		return fmt.Errorf("failed to delete %s: %s", key, message)
+	}
+	s.mu.Lock()
+	delete(s.files, key)
+	s.mu.Unlock()
+	return nil
+}
+
+func (s *FileService) List() ([]Object, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	objects := make([]Object, 0, len(s.files))
+	for _, o := range s.files {
+		objects = append(objects, o)
+	}
+	return objects, nil
+}
+
+func (s *FileService) Cleanup() (int, error) {
+	return cleanup(s, s.opt, "")
+}
@@ -0,0 +1,139 @@
+package storage
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeFileService answers like the file-service: most outcomes are in the
+// statusCode of a 200 body, and a missing download is a 400
+type fakeFileService struct {
+	mu    sync.Mutex
+	files map[string][]byte
+}
+
+func newFakeFileService(t *testing.T) (*fakeFileService, *httptest.Server) {
+	f := &fakeFileService{files: map[string][]byte{}}
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /api/upload", func(w http.ResponseWriter, r *http.Request) {
+		file, header, err := r.FormFile("file")
+		if err != nil {
+			t.Errorf("FormFile: %v", err)
+			return
+		}
+		data, _ := io.ReadAll(file)
+		name := r.FormValue("replaceFileName") + path.Ext(header.Filename)
+		f.mu.Lock()
+		f.files[path.Join("public/images", r.FormValue("filePath"), name)] = data
+		f.mu.Unlock()
+		json.NewEncoder(w).Encode(map[string]any{"statusCode": 200, "message": "success"})
+	})
+	mux.HandleFunc("GET /api/download", func(w http.ResponseWriter, r *http.Request) {
+		f.mu.Lock()
+		data, ok := f.files[path.Join("public/images", r.URL.Query().Get("filePath"), r.URL.Query().Get("filename"))]
+		f.mu.Unlock()
+		if !ok {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]any{"statusCode": 400, "message": "file already exists"})
+			return
+		}
+		w.Write(data)
+	})
+	mux.HandleFunc("DELETE /api/file/{name}", func(w http.ResponseWriter, r *http.Request) {
+		key := path.Join("public/images", r.URL.Query().Get("filePath"), r.PathValue("name"))
+		f.mu.Lock()
+		_, ok := f.files[key]
+		if ok && r.URL.Query().Get("purge") == "true" {
+			delete(f.files, key)
+		}
+		f.mu.Unlock()
+		if !ok {
+			json.NewEncoder(w).Encode(map[string]any{"statusCode": 400, "message": "file not found"})
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]any{"statusCode": 200, "message": "file deleted"})
+	})
+	mux.HandleFunc("GET /api/files", func(w http.ResponseWriter, r *http.Request) {
+		f.mu.Lock()
+		var files []map[string]any
+		for key, data := range f.files {
+			files = append(files, map[string]any{"path": key, "size": len(data), "mtimeMs": 1.7e12})
+		}
+		f.mu.Unlock()
+		json.NewEncoder(w).Encode(map[string]any{"statusCode": 200, "data": files})
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return f, server
+}
+
+func TestFileServiceRoundTrip(t *testing.T) {
+	fake, server := newFakeFileService(t)
+	s := NewFileService(server.URL, "exports", Options{})
+
+	o, err := s.Put("a.xlsx", strings.NewReader("workbook"))
+	if err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	if o.Size != int64(len("workbook")) {
+		t.Errorf("Size = %d, want %d", o.Size, len("workbook"))
+	}
+	r, _, err := s.Open("a.xlsx")
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	data, _ := io.ReadAll(r)
+	if string(data) != "workbook" {
+		t.Errorf("Open = %q", data)
+	}
+
+	if err := s.Delete("a.xlsx"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if len(fake.files) != 0 {
+		t.Errorf("files left on the file-service: %v", fake.files)
+	}
+}
+
+func TestFileServiceMissingDownloadIsNotFound(t *testing.T) {
+	fake, server := newFakeFileService(t)
+	s := NewFileService(server.URL, "exports", Options{})
+	if _, err := s.Put("a.xlsx", strings.NewReader("workbook")); err != nil {
+		t.Fatal(err)
+	}
+	fake.mu.Lock()
+	clear(fake.files)
+	fake.mu.Unlock()
+
+	if _, _, err := s.Open("a.xlsx"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Open = %v, want ErrNotFound", err)
+	}
+}
+
+func TestFileServiceSync(t *testing.T) {
+	fake, server := newFakeFileService(t)
+	fake.files["public/images/exports/old.xlsx"] = []byte("old")
+	fake.files["public/images/other/keep.png"] = []byte("png")
+
+	s := NewFileService(server.URL, "exports", Options{})
+	if err := s.Sync(); err != nil {
+		t.Fatalf("Sync: %v", err)
+	}
+	objects, _ := s.List()
+	if len(objects) != 1 || objects[0].Key != "old.xlsx" || objects[0].Size != 3 {
+		t.Fatalf("List = %v, want old.xlsx only", objects)
+	}
+	if err := s.Delete("old.xlsx"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, ok := fake.files["public/images/exports/old.xlsx"]; ok {
+		t.Error("old.xlsx is still on the file-service")
+	}
+}
@@ -0,0 +1,121 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Local stores files in a directory
+type Local struct {
+	dir string
+	opt Options
+}
+
+func NewLocal(dir string, opt Options) (*Local, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create storage dir: %v", err)
+	}
+	return &Local{dir: dir, opt: opt}, nil
+}
+
+// path resolves key inside the directory, rejecting keys that would leave it
+func (l *Local) path(key string) (string, error) {
+	if key == "" || key != filepath.Base(key) || strings.HasPrefix(key, ".") {
+		return "", fmt.Errorf("invalid key %q", key)
+	}
+	return filepath.Join(l.dir, key), nil
+}
+
+// Put writes r to a unique temporary file and renames it to key in one
+// step, so readers never see a partial file and concurrent Puts of the same
+// key cannot interleave; the last one wins whole
+func (l *Local) Put(key string, r io.Reader) (Object, error) {
+	path, err := l.path(key)
+	if err != nil {
+		return Object{}, err
+	}
+
+	tmp, err := os.CreateTemp(l.dir, ".tmp-*")
+	if err != nil {
+		return Object{}, fmt.Errorf("failed to create file: %v", err)
+	}
+	defer os.Remove(tmp.Name())
+	size, err := io.Copy(tmp, r)
+	var info os.FileInfo
+	if err == nil {
+		info, err = tmp.Stat()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return Object{}, fmt.Errorf("failed to write %s: %v", key, err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return Object{}, fmt.Errorf("failed to store %s: %v", key, err)
+	}
+	return afterPut(l, l.opt, Object{Key: key, Size: size, CreatedAt: info.ModTime()})
+}
+
+func (l *Local) Open(key string) (io.ReadCloser, Object, error) {
+	path, err := l.path(key)
+	if err != nil {
+		return nil, Object{}, err
+	}
+	f, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, Object{}, ErrNotFound
+	}
+	if err != nil {
+		return nil, Object{}, err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, Object{}, err
+	}
+	return f, Object{Key: key, Size: info.Size(), CreatedAt: info.ModTime()}, nil
+}
+
+func (l *Local) Delete(key string) error {
+	path, err := l.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); errors.Is(err, fs.ErrNotExist) {
+		return ErrNotFound
+	} else if err != nil {
+		return err
+	}
+	return nil
+}
+
+// List returns the stored files, skipping the temporary ones of writes in
+// progress
+func (l *Local) List() ([]Object, error) {
+	entries, err := os.ReadDir(l.dir)
+	if err != nil {
+		return nil, err
+	}
+	var objects []Object
+	for _, entry := range entries {
+		if !entry.Type().IsRegular() || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		objects = append(objects, Object{Key: entry.Name(), Size: info.Size(), CreatedAt: info.ModTime()})
+	}
+	return objects, nil
+}
+
+func (l *Local) Cleanup() (int, error) {
+	return cleanup(l, l.opt, "")
+}
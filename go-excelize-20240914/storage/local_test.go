@@ -0,0 +1,48 @@
+package storage
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestLocalConcurrentPutsOfOneKey(t *testing.T) {
+	dir := t.TempDir()
+	l, err := NewLocal(dir, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	contents := map[string]bool{}
+	var wg sync.WaitGroup
+	for i := range 20 {
+		content := strings.Repeat(fmt.Sprint(i%10), 64<<10)
+		contents[content] = true
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := l.Put("a.xlsx", strings.NewReader(content)); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	r, _, err := l.Open("a.xlsx")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	data, _ := io.ReadAll(r)
+	if !contents[string(data)] {
+		t.Errorf("a.xlsx holds interleaved writes of %d bytes", len(data))
+	}
+
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 1 {
+		t.Errorf("files left in the directory: %v", entries)
+	}
+}
@@ -0,0 +1,76 @@
+package storage
+
+import (
+	"bytes"
+	"io"
+	"sync"
+	"time"
+)
+
+// Memory keeps files in memory, for tests and single-instance deployments
+// with small exports
+type Memory struct {
+	mu    sync.RWMutex
+	files map[string]memoryFile
+	opt   Options
+}
+
+type memoryFile struct {
+	data      []byte
+	createdAt time.Time
+}
+
+func NewMemory(opt Options) *Memory {
+	return &Memory{files: map[string]memoryFile{}, opt: opt}
+}
+
+func (m *Memory) Put(key string, r io.Reader) (Object, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return Object{}, err
+	}
+	file := memoryFile{data: data, createdAt: time.Now()}
+
+	m.mu.Lock()
+	m.files[key] = file
+	m.mu.Unlock()
+	return afterPut(m, m.opt, file.object(key))
+}
+
+func (m *Memory) Open(key string) (io.ReadCloser, Object, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	file, ok := m.files[key]
+	if !ok {
+		return nil, Object{}, ErrNotFound
+	}
+	return io.NopCloser(bytes.NewReader(file.data)), file.object(key), nil
+}
+
+func (m *Memory) Delete(key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.files[key]; !ok {
+		return ErrNotFound
+	}
+	delete(m.files, key)
+	return nil
+}
+
+func (m *Memory) List() ([]Object, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	objects := make([]Object, 0, len(m.files))
+	for key, file := range m.files {
+		objects = append(objects, file.object(key))
+	}
+	return objects, nil
+}
+
+func (m *Memory) Cleanup() (int, error) {
+	return cleanup(m, m.opt, "")
+}
+
+func (f memoryFile) object(key string) Object {
+	return Object{Key: key, Size: int64(len(f.data)), CreatedAt: f.createdAt}
+}
@@ -0,0 +1,129 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"sort"
+	"time"
+)
+
+var (
+	ErrNotFound      = errors.New("file not found")
+	ErrQuotaExceeded = errors.New("file exceeds the storage quota")
+)
+
+// Object describes a stored file
+type Object struct {
+	Key       string
+	Size      int64
+	CreatedAt time.Time
+}
+
+// Storage keeps generated export files under unique keys, such as
+// "<job id>.xlsx". Put replaces a file with the same key.
+type Storage interface {
+	Put(key string, r io.Reader) (Object, error)
+	Open(key string) (io.ReadCloser, Object, error)
+	Delete(key string) error
+	List() ([]Object, error)
+	// Cleanup removes the expired files and the oldest ones past the quota
+	Cleanup() (int, error)
+}
+
+// Options configures the retention of a Storage
+type Options struct {
+	// TTL is how long a file is kept, forever when 0
+	TTL time.Duration
+	// Quota bounds the total size of the files in bytes. The oldest files
+	// are removed to make room for new ones. Unlimited when 0.
+	Quota int64
+}
+
+// cleanup enforces opt on s. keep is never removed for the quota, so a new
+// file makes room for itself; it fails with ErrQuotaExceeded when it does
+// not fit on its own.
+func cleanup(s Storage, opt Options, keep string) (int, error) {
+	if opt.TTL <= 0 && opt.Quota <= 0 {
+		return 0, nil
+	}
+	objects, err := s.List()
+	if err != nil {
+		return 0, err
+	}
+	sort.Slice(objects, func(i, j int) bool { return objects[i].CreatedAt.Before(objects[j].CreatedAt) })
+
+	removed, total := 0, int64(0)
+	var kept []Object
+	for _, o := range objects {
+		if opt.TTL > 0 && time.Since(o.CreatedAt) > opt.TTL && o.Key != keep {
+			if err := s.Delete(o.Key); err != nil && !errors.Is(err, ErrNotFound) {
+				return removed, err
+			}
+			removed++
+			continue
+		}
+		kept = append(kept, o)
+		total += o.Size
+	}
+
+	// A file larger than the quota must not evict the others first
+	for _, o := range kept {
+		if o.Key == keep && opt.Quota > 0 && o.Size > opt.Quota {
+			return removed, ErrQuotaExceeded
+		}
+	}
+	for _, o := range kept {
+		if opt.Quota <= 0 || total <= opt.Quota {
+			break
+		}
+		if o.Key == keep {
+			continue
+		}
+		if err := s.Delete(o.Key); err != nil && !errors.Is(err, ErrNotFound) {
+			return removed, err
+		}
+		removed++
+		total -= o.Size
+	}
+	if opt.Quota > 0 && total > opt.Quota {
+		return removed, ErrQuotaExceeded
+	}
+	return removed, nil
+}
+
+// afterPut makes room for the file just stored under key, removing it again
+// when it is larger than the quota
+func afterPut(s Storage, opt Options, o Object) (Object, error) {
+	if _, err := cleanup(s, opt, o.Key); err != nil {
+		if errors.Is(err, ErrQuotaExceeded) {
+			s.Delete(o.Key)
+			return Object{}, fmt.Errorf("%s is %d bytes: %v", o.Key, o.Size, err)
+		}
+		return Object{}, fmt.Errorf("failed to clean up storage: %v", err)
+	}
+	return o, nil
+}
+
+// RunCleanup calls s.Cleanup every interval until ctx is done
+func RunCleanup(ctx context.Context, s Storage, interval time.Duration, logger *slog.Logger) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			removed, err := s.Cleanup()
+			if err != nil {
+				logger.Error("error cleaning up exports.", "error", err)
+				continue
+			}
+			if removed > 0 {
+				logger.Info("Expired exports removed.", "removed", removed)
+			}
+		}
+	}
+}
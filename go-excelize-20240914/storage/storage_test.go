@@ -0,0 +1,89 @@
+package storage
+
+import (
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// put stores size bytes under key as if it had been written age ago
+func put(t *testing.T, m *Memory, key string, size int, age time.Duration) {
+	t.Helper()
+	if _, err := m.Put(key, strings.NewReader(strings.Repeat("x", size))); err != nil {
+		t.Fatalf("Put(%s): %v", key, err)
+	}
+	m.mu.Lock()
+	file := m.files[key]
+	file.createdAt = time.Now().Add(-age)
+	m.files[key] = file
+	m.mu.Unlock()
+}
+
+func keys(t *testing.T, s Storage) []string {
+	t.Helper()
+	objects, err := s.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var keys []string
+	for _, o := range objects {
+		keys = append(keys, o.Key)
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+func TestCleanupRemovesExpiredFiles(t *testing.T) {
+	m := NewMemory(Options{TTL: time.Hour})
+	put(t, m, "new.xlsx", 10, time.Minute)
+	put(t, m, "old.xlsx", 10, 2*time.Hour)
+
+	removed, err := m.Cleanup()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if removed != 1 || !slices.Equal(keys(t, m), []string{"new.xlsx"}) {
+		t.Errorf("removed %d, left %v, want old.xlsx removed", removed, keys(t, m))
+	}
+}
+
+func TestPutEvictsOldestPastQuota(t *testing.T) {
+	m := NewMemory(Options{Quota: 100})
+	put(t, m, "a.xlsx", 40, 3*time.Minute)
+	put(t, m, "b.xlsx", 40, 2*time.Minute)
+	put(t, m, "c.xlsx", 40, time.Minute)
+
+	if got := keys(t, m); !slices.Equal(got, []string{"b.xlsx", "c.xlsx"}) {
+		t.Errorf("files = %v, want the oldest evicted", got)
+	}
+}
+
+func TestPutLargerThanQuota(t *testing.T) {
+	m := NewMemory(Options{Quota: 100})
+	put(t, m, "a.xlsx", 60, time.Minute)
+
+	_, err := m.Put("big.xlsx", strings.NewReader(strings.Repeat("x", 101)))
+	if err == nil || !strings.Contains(err.Error(), ErrQuotaExceeded.Error()) {
+		t.Fatalf("Put = %v, want ErrQuotaExceeded", err)
+	}
+	if got := keys(t, m); !slices.Equal(got, []string{"a.xlsx"}) {
+		t.Errorf("files = %v, want only a.xlsx kept", got)
+	}
+}
+
+func TestLocalCleanup(t *testing.T) {
+	l, err := NewLocal(t.TempDir(), Options{Quota: 100})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{"a.xlsx", "b.xlsx", "c.xlsx"} {
+		if _, err := l.Put(key, strings.NewReader(strings.Repeat("x", 40))); err != nil {
+			t.Fatal(err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if got := keys(t, l); !slices.Equal(got, []string{"b.xlsx", "c.xlsx"}) {
+		t.Errorf("files = %v, want the oldest evicted", got)
+	}
+}
@@ -0,0 +1,112 @@
+package xlsx
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"time"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// About records where a workbook came from. It fills the document
+// properties and, when Sheet is set, a sheet listing every field, so a
+// spreadsheet found later can be traced back to the request that made it.
+type About struct {
+	Title       string
+	Author      string
+	Description string
+	// Sheet names the provenance sheet, which is left out when empty
+	Sheet     string
+	SessionID string
+	// Filters are the query parameters the data was selected with
+	Filters url.Values
+	// Sources are the URLs the data was fetched from
+	Sources []string
+	// Counts are record counts by label, e.g. {"Products": 120}
+	Counts   map[string]int
+	Failures []string
+	// GeneratedAt defaults to the time the workbook is written
+	GeneratedAt time.Time
+}
+
+// writeAbout sets the document properties of f and adds the About sheet
+func writeAbout(f *excelize.File, a *About) error {
+	generatedAt := time.Now()
+	if a != nil && !a.GeneratedAt.IsZero() {
+		generatedAt = a.GeneratedAt
+	}
+	props := &excelize.DocProperties{
+		Created:  generatedAt.UTC().Format(time.RFC3339),
+		Modified: generatedAt.UTC().Format(time.RFC3339),
+	}
+	if a != nil {
+		props.Title, props.Creator, props.LastModifiedBy = a.Title, a.Author, a.Author
+		props.Description, props.Identifier = a.Description, a.SessionID
+	}
+	if err := f.SetDocProps(props); err != nil {
+		return fmt.Errorf("failed to set document properties: %v", err)
+	}
+	if a == nil || a.Sheet == "" {
+		return nil
+	}
+
+	if idx, _ := f.GetSheetIndex(a.Sheet); idx != -1 {
+		return fmt.Errorf("duplicate sheet name %s", a.Sheet)
+	}
+	if _, err := f.NewSheet(a.Sheet); err != nil {
+		return fmt.Errorf("failed to create sheet %s: %v", a.Sheet, err)
+	}
+
+	rows := [][]any{{"Title", a.Title}, {"Author", a.Author}, {"Session ID", a.SessionID}, {"Generated At", generatedAt}}
+	keys := make([]string, 0, len(a.Filters))
+	for key := range a.Filters {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		for _, value := range a.Filters[key] {
+			rows = append(rows, []any{"Filter " + key, value})
+		}
+	}
+	for _, source := range a.Sources {
+		rows = append(rows, []any{"Source", source})
+	}
+	labels := make([]string, 0, len(a.Counts))
+	for label := range a.Counts {
+		labels = append(labels, label)
+	}
+	sort.Strings(labels)
+	for _, label := range labels {
+		rows = append(rows, []any{label, a.Counts[label]})
+	}
+	for _, failure := range a.Failures {
+		rows = append(rows, []any{"Failure", failure})
+	}
+
+	header, err := newHeaderStyle(f, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create header style: %v", err)
+	}
+	if err := f.SetSheetRow(a.Sheet, "A1", &[]any{"Field", "Value"}); err != nil {
+		return err
+	}
+	f.SetCellStyle(a.Sheet, "A1", "B1", header)
+	for i, row := range rows {
+		cell, _ := excelize.CoordinatesToCellName(1, i+2)
+		if err := f.SetSheetRow(a.Sheet, cell, &row); err != nil {
+			return err
+		}
+	}
+
+	dateFormat := defaultDateFormat
+	date, err := f.NewStyle(&excelize.Style{CustomNumFmt: &dateFormat, Alignment: &excelize.Alignment{Horizontal: "left"}})
+	if err != nil {
+		return fmt.Errorf("failed to create date style: %v", err)
+	}
+	// Generated At is the fourth field
+	f.SetCellStyle(a.Sheet, "B5", "B5", date)
+	f.SetColWidth(a.Sheet, "A", "A", 24)
+	f.SetColWidth(a.Sheet, "B", "B", 60)
+	return nil
+}
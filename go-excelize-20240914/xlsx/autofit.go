@@ -0,0 +1,157 @@
+package xlsx
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"golang.org/x/text/width"
+)
+
+const (
+	defaultMinWidth = 8
+	defaultMaxWidth = 60
+	// autoFitPadding leaves room for the cell margins and the filter button
+	autoFitPadding = 2
+	// autoFitSampleRows is the number of rows the stream writer buffers to
+	// measure before it has to fix the column widths
+	autoFitSampleRows = 1000
+)
+
+// autoFit sets the width of every column without an explicit width from the
+// widest rendered value of rows, bounded by MinWidth and MaxWidth.
+func autoFit(columns []column, rows [][]any, opt XlsxOptions) {
+	minWidth, maxWidth := opt.MinWidth, opt.MaxWidth
+	if minWidth <= 0 {
+		minWidth = defaultMinWidth
+	}
+	if maxWidth <= 0 {
+		maxWidth = defaultMaxWidth
+	}
+
+	for j := range columns {
+		c := &columns[j]
+		if c.width > 0 || (c.image && opt.ImageFetcher != nil) {
+			continue
+		}
+
+		w := textWidth(c.header)
+		for _, row := range rows {
+			w = max(w, textWidth(renderedText(row[j], c.numFmt)))
+		}
+		c.width = math.Min(math.Max(float64(w+autoFitPadding), minWidth), maxWidth)
+	}
+}
+
+// textWidth is the number of character cells text takes up: East Asian wide
+// characters count twice and combining marks, such as Thai vowels and tone
+// marks above or below a consonant, take no space. Multi-line text is as
+// wide as its longest line.
+func textWidth(text string) int {
+	widest := 0
+	for _, line := range strings.Split(text, "\n") {
+		w := 0
+		for _, r := range line {
+			switch {
+			case unicode.In(r, unicode.Mn, unicode.Me, unicode.Cf):
+			case isWide(r):
+				w += 2
+			default:
+				w++
+			}
+		}
+		widest = max(widest, w)
+	}
+	return widest
+}
+
+func isWide(r rune) bool {
+	switch width.LookupRune(r).Kind() {
+	case width.EastAsianWide, width.EastAsianFullwidth:
+		return true
+	}
+	return false
+}
+
+// renderedText approximates how Excel displays v with the number format
+// numFmt, which is what the column has to fit.
+func renderedText(v any, numFmt string) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case time.Time:
+		if numFmt != "" {
+			return formatCode(numFmt)
+		}
+	case int64:
+		return renderNumber(float64(v), numFmt)
+	case uint64:
+		return renderNumber(float64(v), numFmt)
+	case float64:
+		return renderNumber(v, numFmt)
+	}
+	return formatText(v)
+}
+
+// renderNumber applies the decimals and thousands separator of numFmt
+func renderNumber(n float64, numFmt string) string {
+	section, _, _ := strings.Cut(numFmt, ";")
+	if section == "" || strings.EqualFold(section, "General") {
+		return strconv.FormatFloat(n, 'f', -1, 64)
+	}
+
+	decimals := -1
+	if _, frac, ok := strings.Cut(section, "."); ok {
+		decimals = strings.Count(frac, "0") + strings.Count(frac, "#")
+	} else if strings.ContainsAny(section, "0#") {
+		decimals = 0
+	}
+
+	text := strconv.FormatFloat(n, 'f', decimals, 64)
+	if strings.Contains(section, ",") {
+		text = groupThousands(text)
+	}
+	// Literal prefixes and suffixes such as currency symbols
+	return strings.Repeat("x", textWidth(formatCode(strings.Trim(section, "#,0.")))) + text
+}
+
+func groupThousands(text string) string {
+	sign := ""
+	if strings.HasPrefix(text, "-") {
+		sign, text = "-", text[1:]
+	}
+	whole, frac, hasFrac := strings.Cut(text, ".")
+
+	var b strings.Builder
+	for i, r := range whole {
+		if i > 0 && (len(whole)-i)%3 == 0 {
+			b.WriteByte(',')
+		}
+		b.WriteRune(r)
+	}
+	if hasFrac {
+		return sign + b.String() + "." + frac
+	}
+	return sign + b.String()
+}
+
+// formatCode strips the quoting and locale parts of a number format so its
+// length approximates the rendered value
+func formatCode(numFmt string) string {
+	var b strings.Builder
+	inBracket := false
+	for _, r := range numFmt {
+		switch {
+		case r == '[':
+			inBracket = true
+		case r == ']':
+			inBracket = false
+		case inBracket, r == '"', r == '\\', r == '@', r == ';':
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
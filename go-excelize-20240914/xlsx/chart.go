@@ -0,0 +1,219 @@
+package xlsx
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/xuri/excelize/v2"
+)
+
+var chartTypes = map[string]excelize.ChartType{
+	"col":      excelize.Col,
+	"bar":      excelize.Bar,
+	"line":     excelize.Line,
+	"area":     excelize.Area,
+	"pie":      excelize.Pie,
+	"doughnut": excelize.Doughnut,
+	"scatter":  excelize.Scatter,
+}
+
+// Chart adds a chart sheet plotting columns of the data sheet, for example
+// the stock per product:
+//
+//	xlsx.Chart{Sheet: "Stock Chart", Category: "Product Name", Values: []string{"Stock"}}
+//
+// Type is one of col (the default), bar, line, area, pie, doughnut or
+// scatter. Every column in Values becomes a series named after its header.
+type Chart struct {
+	Sheet    string
+	Title    string
+	Type     string
+	Category string
+	Values   []string
+}
+
+// PivotTable adds a worksheet with a pivot table over the data range, for
+// example the number of products per price:
+//
+//	xlsx.PivotTable{
+//		Sheet: "Price Pivot",
+//		Rows:  []string{"Unit Price"},
+//		Data:  []xlsx.PivotValue{{Column: "Product Name", Func: "Count"}},
+//	}
+//
+// Excel refreshes the pivot table when the workbook is opened.
+type PivotTable struct {
+	Sheet   string
+	Rows    []string
+	Columns []string
+	Filter  []string
+	Data    []PivotValue
+	// Style is one of Excel's pivot styles, e.g. "PivotStyleLight16"
+	Style string
+}
+
+// PivotValue summarizes Column with Func, which is Sum (the default),
+// Average, Count, Max, Min or any other pivot subtotal function.
+type PivotValue struct {
+	Column string
+	Func   string
+	Name   string
+}
+
+// addDashboards adds the chart and pivot sheets of every generated data
+// sheet. They come after all data sheets so their ranges can refer to any
+// of them.
+func (f *Xlsx) addDashboards() error {
+	for _, s := range f.sheets {
+		if s.templated {
+			continue
+		}
+		for _, chart := range s.opt.Charts {
+			if err := f.addChart(s, chart); err != nil {
+				return fmt.Errorf("failed to add chart %s: %v", chart.Sheet, err)
+			}
+		}
+		for _, pivot := range s.opt.PivotTables {
+			if err := f.addPivotTable(s, pivot); err != nil {
+				return fmt.Errorf("failed to add pivot table %s: %v", pivot.Sheet, err)
+			}
+		}
+	}
+	return nil
+}
+
+func (f *Xlsx) addChart(s *sheet, chart Chart) error {
+	typ := chart.Type
+	if typ == "" {
+		typ = "col"
+	}
+	chartType, ok := chartTypes[typ]
+	if !ok {
+		return fmt.Errorf("unknown chart type %q", typ)
+	}
+
+	categories, err := columnRef(s, chart.Category)
+	if err != nil {
+		return err
+	}
+
+	c := &excelize.Chart{
+		Type:   chartType,
+		Legend: excelize.ChartLegend{Position: "bottom"},
+	}
+	if chart.Title != "" {
+		c.Title = []excelize.RichTextRun{{Text: chart.Title}}
+	}
+	for _, name := range chart.Values {
+		values, err := columnRef(s, name)
+		if err != nil {
+			return err
+		}
+		c.Series = append(c.Series, excelize.ChartSeries{
+			Name:       headerRef(s, name),
+			Categories: categories,
+			Values:     values,
+		})
+	}
+	if len(c.Series) == 0 {
+		return fmt.Errorf("chart has no values")
+	}
+	return f.file.AddChartSheet(chart.Sheet, c)
+}
+
+func (f *Xlsx) addPivotTable(s *sheet, pivot PivotTable) error {
+	if idx, _ := f.file.GetSheetIndex(pivot.Sheet); idx != -1 {
+		return fmt.Errorf("duplicate sheet name %s", pivot.Sheet)
+	}
+	if _, err := f.file.NewSheet(pivot.Sheet); err != nil {
+		return err
+	}
+
+	ref, err := dataRange(len(s.columns), len(s.rows))
+	if err != nil {
+		return err
+	}
+	// The pivot table grows to its content when Excel refreshes it
+	end, err := excelize.CoordinatesToCellName(len(pivot.Rows)+len(pivot.Data)+1, len(s.rows)+4)
+	if err != nil {
+		return err
+	}
+
+	opts := &excelize.PivotTableOptions{
+		DataRange:           s.name + "!" + ref,
+		PivotTableRange:     pivot.Sheet + "!A3:" + end,
+		RowGrandTotals:      true,
+		ColGrandTotals:      true,
+		ShowDrill:           true,
+		ShowRowHeaders:      true,
+		ShowColHeaders:      true,
+		ShowLastColumn:      true,
+		PivotTableStyleName: pivot.Style,
+	}
+	if opts.Rows, err = pivotFields(s, pivot.Rows); err != nil {
+		return err
+	}
+	if opts.Columns, err = pivotFields(s, pivot.Columns); err != nil {
+		return err
+	}
+	if opts.Filter, err = pivotFields(s, pivot.Filter); err != nil {
+		return err
+	}
+	for _, v := range pivot.Data {
+		j := columnIndex(s.columns, v.Column)
+		if j < 0 {
+			return fmt.Errorf("unknown column %q", v.Column)
+		}
+		fn := v.Func
+		if fn == "" {
+			fn = "Sum"
+		}
+		name := v.Name
+		if name == "" {
+			name = fn + " of " + s.columns[j].header
+		}
+		opts.Data = append(opts.Data, excelize.PivotTableField{
+			Data:     s.columns[j].header,
+			Name:     name,
+			Subtotal: fn,
+		})
+	}
+	return f.file.AddPivotTable(opts)
+}
+
+func pivotFields(s *sheet, names []string) ([]excelize.PivotTableField, error) {
+	var fields []excelize.PivotTableField
+	for _, name := range names {
+		j := columnIndex(s.columns, name)
+		if j < 0 {
+			return nil, fmt.Errorf("unknown column %q", name)
+		}
+		fields = append(fields, excelize.PivotTableField{Data: s.columns[j].header, DefaultSubtotal: true})
+	}
+	return fields, nil
+}
+
+// columnRef is the absolute reference to the data cells of a column, as
+// used by chart series
+func columnRef(s *sheet, name string) (string, error) {
+	j := columnIndex(s.columns, name)
+	if j < 0 {
+		return "", fmt.Errorf("unknown column %q", name)
+	}
+	col, err := excelize.ColumnNumberToName(j + 1)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s!$%s$2:$%s$%d", quoteSheet(s.name), col, col, max(len(s.rows), 1)+1), nil
+}
+
+// headerRef is the absolute reference to the header cell of a column
+func headerRef(s *sheet, name string) string {
+	col, _ := excelize.ColumnNumberToName(columnIndex(s.columns, name) + 1)
+	return fmt.Sprintf("%s!$%s$1", quoteSheet(s.name), col)
+}
+
+// quoteSheet quotes a sheet name for use in a cell reference
+func quoteSheet(name string) string {
+	return "'" + strings.ReplaceAll(name, "'", "''") + "'"
+}
@@ -0,0 +1,104 @@
+package xlsx
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// utf8BOM lets Excel detect UTF-8 so Thai text in CSV files is not garbled
+const utf8BOM = "\xEF\xBB\xBF"
+
+// Delimited exports rows as CSV or TSV text.
+type Delimited struct {
+	columns []column
+	rows    [][]any
+	comma   rune
+	bom     bool
+	format  Format
+}
+
+// NewCSV creates a comma separated export, prefixed with a UTF-8 BOM so
+// Excel opens it with the right encoding.
+func NewCSV[T any](tData []T, opt XlsxOptions) *Delimited {
+	columns, rows := tabulate(tData, opt)
+	return &Delimited{columns: columns, rows: rows, comma: ',', bom: true, format: FormatCSV}
+}
+
+// NewTSV creates a tab separated export.
+func NewTSV[T any](tData []T, opt XlsxOptions) *Delimited {
+	columns, rows := tabulate(tData, opt)
+	return &Delimited{columns: columns, rows: rows, comma: '\t', format: FormatTSV}
+}
+
+func (d *Delimited) ContentType() string {
+	return formatContentTypes[d.format] + "; charset=utf-8"
+}
+
+func (d *Delimited) Extension() string {
+	return string(d.format)
+}
+
+func (d *Delimited) Write(w io.Writer) error {
+	if d.bom {
+		if _, err := io.WriteString(w, utf8BOM); err != nil {
+			return err
+		}
+	}
+
+	cw := csv.NewWriter(w)
+	cw.Comma = d.comma
+
+	record := make([]string, len(d.columns))
+	for i, c := range d.columns {
+		record[i] = escapeFormula(c.header)
+	}
+	if err := cw.Write(record); err != nil {
+		return err
+	}
+
+	for _, row := range d.rows {
+		for i, v := range row {
+			record[i] = formatText(v)
+			if _, ok := v.(string); ok {
+				record[i] = escapeFormula(record[i])
+			}
+		}
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
+
+// formatText renders a cell value for the text based formats
+func formatText(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case time.Time:
+		return v.Format(time.RFC3339)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case float32:
+		return strconv.FormatFloat(float64(v), 'f', -1, 32)
+	}
+	return fmt.Sprint(v)
+}
+
+// escapeFormula keeps spreadsheet applications from running text that looks
+// like a formula, such as =HYPERLINK(...), by prefixing it with a quote.
+// Numbers are not text and are never escaped.
+func escapeFormula(s string) string {
+	if s != "" && strings.ContainsRune("=+-@\t\r", rune(s[0])) {
+		return "'" + s
+	}
+	return s
+}
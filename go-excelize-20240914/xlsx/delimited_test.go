@@ -0,0 +1,51 @@
+package xlsx
+
+import (
+	"bytes"
+	"encoding/csv"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDelimitedEscapesFormulas(t *testing.T) {
+	type row struct {
+		Name  string
+		Price float64
+	}
+	data := []row{
+		{"=HYPERLINK(\"http://evil\")", -5},
+		{"+1", 1},
+		{"-1", 2},
+		{"@SUM(A1)", 3},
+		{"\tcmd", 4},
+		{"\rcmd", 5},
+		{"a=b", 6},
+	}
+	want := [][]string{
+		{"Name", "Price"},
+		{"'=HYPERLINK(\"http://evil\")", "-5"},
+		{"'+1", "1"},
+		{"'-1", "2"},
+		{"'@SUM(A1)", "3"},
+		{"'\tcmd", "4"},
+		{"'\rcmd", "5"},
+		{"a=b", "6"},
+	}
+
+	for _, e := range []*Delimited{NewCSV(data, XlsxOptions{}), NewTSV(data, XlsxOptions{})} {
+		var buf bytes.Buffer
+		if err := e.Write(&buf); err != nil {
+			t.Fatalf("%s: Write: %v", e.format, err)
+		}
+		r := csv.NewReader(strings.NewReader(strings.TrimPrefix(buf.String(), utf8BOM)))
+		r.Comma = e.comma
+		got, err := r.ReadAll()
+		if err != nil {
+			t.Fatalf("%s: ReadAll: %v", e.format, err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%s rows = %q, want %q", e.format, got, want)
+		}
+	}
+}
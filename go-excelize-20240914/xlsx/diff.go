@@ -0,0 +1,261 @@
+package xlsx
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// changedHighlight is the light yellow fill of changed cells
+const changedHighlight = "FFEB9C"
+
+// summaryFormula matches the formulas of the summary rows below exported
+// data, e.g. SUM(D2:D120)
+var summaryFormula = regexp.MustCompile(`^[A-Z]+\(([A-Z]+)\d+:([A-Z]+)(\d+)\)$`)
+
+// DiffOptions configures a diff report between two snapshots.
+type DiffOptions struct {
+	// Key is the column identifying a row in both snapshots, e.g. "ID"
+	Key string
+	// FileName is where SaveExcelFile writes the report
+	FileName string
+	// Headers narrows the columns compared by Diff
+	Headers []string
+	// Sheet and HeaderRow select the table compared by DiffWorkbooks, as
+	// in ReadOptions
+	Sheet     string
+	HeaderRow int
+}
+
+// Diff compares two datasets by their Key column and returns a report with
+// the rows only in after on an Added sheet, the rows only in before on a
+// Removed sheet, and the rows whose values differ on a Changed sheet. Changed
+// cells show the new value, are highlighted and carry the old value in a
+// comment.
+func Diff[T any](before, after []T, opt DiffOptions) (*Xlsx, error) {
+	// Tabulate both at once so maps and slices get the same columns
+	all := append(append(make([]T, 0, len(before)+len(after)), before...), after...)
+	columns, rows := tabulate(all, XlsxOptions{Headers: opt.Headers})
+	return diffReport(columns, rows[:len(before)], rows[len(before):], opt)
+}
+
+// DiffWorkbooks is Diff for two previously exported workbooks. Cells are
+// compared as displayed, and columns are matched by header.
+func DiffWorkbooks(before, after io.Reader, opt DiffOptions) (*Xlsx, error) {
+	beforeHeaders, beforeRows, err := readTable(before, opt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the before workbook: %v", err)
+	}
+	afterHeaders, afterRows, err := readTable(after, opt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the after workbook: %v", err)
+	}
+
+	// Columns of the after workbook come first, then removed ones
+	var columns []column
+	positions := map[string]int{}
+	for _, header := range append(afterHeaders, beforeHeaders...) {
+		if _, ok := positions[header]; ok || header == "" {
+			continue
+		}
+		positions[header] = len(columns)
+		columns = append(columns, column{name: header, field: header, header: header})
+	}
+
+	return diffReport(columns, alignRows(beforeRows, beforeHeaders, positions), alignRows(afterRows, afterHeaders, positions), opt)
+}
+
+// readTable reads the header and data rows of a sheet as displayed
+func readTable(r io.Reader, opt DiffOptions) ([]string, [][]string, error) {
+	f, err := excelize.OpenReader(r)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer f.Close()
+
+	sheet := opt.Sheet
+	if sheet == "" {
+		sheet = f.GetSheetName(0)
+	}
+	headerRow := max(opt.HeaderRow, 1)
+
+	rows, err := f.GetRows(sheet)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(rows) < headerRow {
+		return nil, nil, fmt.Errorf("sheet %s has no header row %d", sheet, headerRow)
+	}
+
+	headers := make([]string, len(rows[headerRow-1]))
+	for i, header := range rows[headerRow-1] {
+		headers[i] = strings.TrimSpace(header)
+	}
+	// Summary rows sit at the end and are not part of the data
+	end := len(rows)
+	for end > headerRow && (isBlankRow(rows[end-1]) || isSummaryRow(f, sheet, end, rows[end-1])) {
+		end--
+	}
+	var data [][]string
+	for _, row := range rows[headerRow:end] {
+		if !isBlankRow(row) {
+			data = append(data, row)
+		}
+	}
+	return headers, data, nil
+}
+
+// isSummaryRow reports whether row number n aggregates the rows above it
+func isSummaryRow(f *excelize.File, sheet string, n int, row []string) bool {
+	for j := range row {
+		cell, err := excelize.CoordinatesToCellName(j+1, n)
+		if err != nil {
+			return false
+		}
+		formula, _ := f.GetCellFormula(sheet, cell)
+		m := summaryFormula.FindStringSubmatch(strings.TrimPrefix(formula, "="))
+		if m == nil || m[1] != m[2] {
+			continue
+		}
+		if last, err := strconv.Atoi(m[3]); err == nil && last < n {
+			return true
+		}
+	}
+	return false
+}
+
+// alignRows places the cells of rows in the report columns of their headers
+func alignRows(rows [][]string, headers []string, positions map[string]int) [][]any {
+	aligned := make([][]any, len(rows))
+	for i, row := range rows {
+		aligned[i] = make([]any, len(positions))
+		for j, cell := range row {
+			if j < len(headers) && headers[j] != "" && cell != "" {
+				aligned[i][positions[headers[j]]] = cell
+			}
+		}
+	}
+	return aligned
+}
+
+func diffReport(columns []column, before, after [][]any, opt DiffOptions) (*Xlsx, error) {
+	key := columnIndex(columns, opt.Key)
+	if key < 0 {
+		return nil, fmt.Errorf("unknown key column %q", opt.Key)
+	}
+	beforeKeys, err := indexRows(before, key, opt.Key)
+	if err != nil {
+		return nil, err
+	}
+	afterKeys, err := indexRows(after, key, opt.Key)
+	if err != nil {
+		return nil, err
+	}
+
+	var added, removed, changed [][]any
+	changes := map[[2]int]any{}
+	for _, row := range after {
+		i, ok := beforeKeys[formatText(row[key])]
+		if !ok {
+			added = append(added, row)
+			continue
+		}
+
+		modified := false
+		for j := range columns {
+			if formatText(row[j]) != formatText(before[i][j]) {
+				changes[[2]int{len(changed), j}] = before[i][j]
+				modified = true
+			}
+		}
+		if modified {
+			changed = append(changed, row)
+		}
+	}
+	for _, row := range before {
+		if _, ok := afterKeys[formatText(row[key])]; !ok {
+			removed = append(removed, row)
+		}
+	}
+
+	x := NewWorkbook(XlsxOptions{FileName: opt.FileName})
+	for _, s := range []*sheet{
+		{name: "Added", rows: added},
+		{name: "Removed", rows: removed},
+		{name: "Changed", rows: changed, changes: changes},
+	} {
+		s.opt = XlsxOptions{Sheet: s.name, AutoFit: true, FreezeHeader: true}
+		s.columns = prepareColumns(columns, s.rows, s.opt)
+		autoFit(s.columns, s.rows, s.opt)
+		x.sheets = append(x.sheets, s)
+	}
+	return x, nil
+}
+
+// indexRows maps the key of every row to its position
+func indexRows(rows [][]any, key int, name string) (map[string]int, error) {
+	index := make(map[string]int, len(rows))
+	for i, row := range rows {
+		k := formatText(row[key])
+		if k == "" {
+			return nil, fmt.Errorf("row %d has no %s", i+1, name)
+		}
+		if _, ok := index[k]; ok {
+			return nil, fmt.Errorf("duplicate %s %q", name, k)
+		}
+		index[k] = i
+	}
+	return index, nil
+}
+
+// writeChanges highlights the changed cells of a diff sheet and notes their
+// previous value in a comment
+func (f *Xlsx) writeChanges(s *sheet) error {
+	if len(s.changes) == 0 {
+		return nil
+	}
+
+	styles := make([]int, len(s.columns))
+	for i := range s.rows {
+		for j, c := range s.columns {
+			was, ok := s.changes[[2]int{i, j}]
+			if !ok {
+				continue
+			}
+
+			if styles[j] == 0 {
+				style := &excelize.Style{
+					Fill: excelize.Fill{Type: "pattern", Pattern: 1, Color: []string{changedHighlight}},
+				}
+				if c.numFmt != "" {
+					numFmt := c.numFmt
+					style.CustomNumFmt = &numFmt
+				}
+				id, err := f.file.NewStyle(style)
+				if err != nil {
+					return fmt.Errorf("failed to create highlight style: %v", err)
+				}
+				styles[j] = id
+			}
+
+			cell, err := excelize.CoordinatesToCellName(j+1, i+2)
+			if err != nil {
+				return err
+			}
+			f.file.SetCellStyle(s.name, cell, cell, styles[j])
+
+			text := formatText(was)
+			if text == "" {
+				text = "(empty)"
+			}
+			if err := f.file.AddComment(s.name, excelize.Comment{Cell: cell, Author: "diff", Text: "Was: " + text}); err != nil {
+				return fmt.Errorf("failed to comment %s: %v", cell, err)
+			}
+		}
+	}
+	return nil
+}
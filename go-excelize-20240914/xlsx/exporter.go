@@ -0,0 +1,111 @@
+package xlsx
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Exporter writes a dataset in one output format. All formats share the
+// column mapping of the xlsx struct tags and XlsxOptions.Headers.
+type Exporter interface {
+	ContentType() string
+	Extension() string
+	Write(w io.Writer) error
+}
+
+type Format string
+
+const (
+	FormatXLSX      Format = "xlsx"
+	FormatCSV       Format = "csv"
+	FormatTSV       Format = "tsv"
+	FormatJSONLines Format = "ndjson"
+)
+
+var formatContentTypes = map[Format]string{
+	FormatXLSX:      ContentType,
+	FormatCSV:       "text/csv",
+	FormatTSV:       "text/tab-separated-values",
+	FormatJSONLines: "application/x-ndjson",
+}
+
+// NewExporter returns the exporter for format holding tData.
+func NewExporter[T any](format Format, tData []T, opt XlsxOptions) (Exporter, error) {
+	switch format {
+	case FormatXLSX:
+		return NewXlsx(tData, opt), nil
+	case FormatCSV:
+		return NewCSV(tData, opt), nil
+	case FormatTSV:
+		return NewTSV(tData, opt), nil
+	case FormatJSONLines:
+		return NewJSONLines(tData, opt), nil
+	}
+	return nil, fmt.Errorf("unsupported export format %q", format)
+}
+
+// Negotiate picks the export format from an explicit format value, such as
+// the ?format= query parameter, or else from an Accept header. It reports
+// false when neither asks for a supported format.
+func Negotiate(format, accept string) (Format, bool) {
+	if format != "" {
+		f := Format(strings.ToLower(format))
+		if f == "jsonl" {
+			f = FormatJSONLines
+		}
+		_, ok := formatContentTypes[f]
+		return f, ok
+	}
+
+	type mediaRange struct {
+		mediaType string
+		q         float64
+	}
+	var ranges []mediaRange
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, _ := strings.Cut(part, ";")
+		q := 1.0
+		for _, param := range strings.Split(params, ";") {
+			if v, ok := strings.CutPrefix(strings.TrimSpace(param), "q="); ok {
+				if n, err := strconv.ParseFloat(v, 64); err == nil {
+					q = n
+				}
+			}
+		}
+		ranges = append(ranges, mediaRange{strings.ToLower(strings.TrimSpace(mediaType)), q})
+	}
+	sort.SliceStable(ranges, func(i, j int) bool {
+		return ranges[i].q > ranges[j].q
+	})
+
+	for _, r := range ranges {
+		if r.q <= 0 {
+			continue
+		}
+		for f, contentType := range formatContentTypes {
+			if r.mediaType == contentType {
+				return f, true
+			}
+		}
+	}
+	return "", false
+}
+
+func (f *Xlsx) ContentType() string {
+	return ContentType
+}
+
+func (f *Xlsx) Extension() string {
+	return string(FormatXLSX)
+}
+
+func (s *StreamWriter[T]) ContentType() string {
+	return ContentType
+}
+
+func (s *StreamWriter[T]) Extension() string {
+	return string(FormatXLSX)
+}
@@ -0,0 +1,30 @@
+package xlsx
+
+import "testing"
+
+func TestNegotiate(t *testing.T) {
+	tests := []struct {
+		format, accept string
+		want           Format
+		ok             bool
+	}{
+		{"", "", "", false},
+		{"csv", "", FormatCSV, true},
+		{"XLSX", "text/csv", FormatXLSX, true},
+		{"jsonl", "", FormatJSONLines, true},
+		{"pdf", "text/csv", "pdf", false},
+		{"", "text/csv", FormatCSV, true},
+		{"", "text/html, text/tab-separated-values", FormatTSV, true},
+		{"", "text/csv;q=0.5, application/x-ndjson", FormatJSONLines, true},
+		{"", "text/csv;q=0, " + ContentType + ";q=0.1", FormatXLSX, true},
+		{"", "text/csv;q=0", "", false},
+		{"", "*/*", "", false},
+	}
+
+	for _, tt := range tests {
+		got, ok := Negotiate(tt.format, tt.accept)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("Negotiate(%q, %q) = %q, %v, want %q, %v", tt.format, tt.accept, got, ok, tt.want, tt.ok)
+		}
+	}
+}
@@ -0,0 +1,49 @@
+package xlsx
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// saveFile writes path through a unique temporary file in the same
+// directory, which replaces path in a single rename once it is complete.
+// Readers never see a partial workbook, and of concurrent saves to the same
+// path the last one wins whole.
+func saveFile(path string, write func(w io.Writer) error) error {
+	if path == "" {
+		return errors.New("no file name to save to")
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+"-*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create file: %v", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	err = write(tmp)
+	if err == nil {
+		err = tmp.Chmod(0o644)
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write %s: %v", path, err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to save %s: %v", path, err)
+	}
+	return nil
+}
+
+// removeFile removes path. A missing file is not an error.
+func removeFile(path string) error {
+	if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("failed to remove existing file: %v", err)
+	}
+	return nil
+}
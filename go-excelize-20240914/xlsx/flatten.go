@@ -0,0 +1,273 @@
+package xlsx
+
+import (
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	// defaultFlattenDepth is the number of nesting levels flattened into
+	// columns when XlsxOptions.FlattenDepth is 0
+	defaultFlattenDepth = 3
+	// maxElementColumns bounds the columns of a single slice; longer slices
+	// are written as JSON
+	maxElementColumns = 50
+)
+
+var (
+	textMarshalerType = reflect.TypeFor[encoding.TextMarshaler]()
+	jsonMarshalerType = reflect.TypeFor[json.Marshaler]()
+)
+
+// segment is one step from a row to a nested value: a struct field, a map
+// key or a slice element.
+type segment struct {
+	kind  reflect.Kind
+	index []int
+	// owner is the struct type index belongs to
+	owner reflect.Type
+	key   string
+	elem  int
+}
+
+// step follows the segment from v, returning the invalid Value when the
+// value is missing.
+func (s segment) step(v reflect.Value) reflect.Value {
+	v = indirect(v)
+	switch {
+	case !v.IsValid():
+		return v
+	case s.kind == reflect.Struct && v.Kind() == reflect.Struct:
+		// Rows of []any may hold other struct types than the schema's
+		if s.owner != nil && v.Type() != s.owner {
+			return reflect.Value{}
+		}
+		f, err := v.FieldByIndexErr(s.index)
+		if err != nil {
+			return reflect.Value{}
+		}
+		return f
+	case s.kind == reflect.Map && v.Kind() == reflect.Map && v.Type().Key().Kind() == reflect.String:
+		return v.MapIndex(reflect.ValueOf(s.key).Convert(v.Type().Key()))
+	case s.kind == reflect.Slice && (v.Kind() == reflect.Slice || v.Kind() == reflect.Array):
+		if s.elem < v.Len() {
+			return v.Index(s.elem)
+		}
+	}
+	return reflect.Value{}
+}
+
+// indirect dereferences pointers and interfaces, returning the invalid
+// Value for nil.
+func indirect(v reflect.Value) reflect.Value {
+	for v.IsValid() && (v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface) {
+		if v.IsNil() {
+			return reflect.Value{}
+		}
+		v = v.Elem()
+	}
+	return v
+}
+
+func flattenDepth(opt XlsxOptions) int {
+	if opt.FlattenDepth == 0 {
+		return defaultFlattenDepth
+	}
+	return opt.FlattenDepth
+}
+
+// flatten replaces columns holding nested structs, maps and slices with a
+// column per field, key or element, up to depth levels: `data.name`,
+// `tags[0]`. Struct fields are expanded from their type; maps and slices
+// from the keys and lengths found in rows, in sorted key order.
+func flatten(columns []column, rows []reflect.Value, depth int) []column {
+	if depth <= 0 {
+		return columns
+	}
+
+	var flat []column
+	for _, c := range columns {
+		children := c.children(rows)
+		if children == nil {
+			flat = append(flat, c)
+			continue
+		}
+		flat = append(flat, flatten(children, rows, depth-1)...)
+	}
+	return flat
+}
+
+// children returns the columns c expands into, or nil if it holds a single
+// value.
+func (c column) children(rows []reflect.Value) []column {
+	t := c.typ
+	for t != nil && t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t != nil && t.Kind() == reflect.Struct && !isScalarType(t) {
+		var children []column
+		for _, fc := range columnsOf(t) {
+			children = append(children, c.child(fc.name, "."+fc.header, fc.typ, segment{kind: reflect.Struct, index: fc.index, owner: fc.owner}))
+		}
+		return children
+	}
+	if t != nil && !isNestedKind(t.Kind()) {
+		return nil
+	}
+
+	// Interfaces, maps and slices are expanded from the values themselves
+	keys := map[string]reflect.Type{}
+	elems := 0
+	var elemType reflect.Type
+	for _, row := range rows {
+		v := indirect(c.lookup(row))
+		if !v.IsValid() || isScalarType(v.Type()) {
+			continue
+		}
+		switch v.Kind() {
+		case reflect.Map:
+			if v.Type().Key().Kind() != reflect.String {
+				return nil
+			}
+			for _, k := range v.MapKeys() {
+				if _, ok := keys[k.String()]; !ok {
+					keys[k.String()] = staticType(v.Type().Elem())
+				}
+			}
+		case reflect.Slice, reflect.Array:
+			if v.Type().Elem().Kind() == reflect.Uint8 {
+				return nil
+			}
+			elems = max(elems, v.Len())
+			elemType = staticType(v.Type().Elem())
+		case reflect.Struct:
+			c.typ = v.Type()
+			return c.children(nil)
+		}
+	}
+
+	switch {
+	case len(keys) > 0 && elems == 0:
+		names := make([]string, 0, len(keys))
+		for k := range keys {
+			names = append(names, k)
+		}
+		sort.Strings(names)
+
+		children := make([]column, len(names))
+		for i, k := range names {
+			children[i] = c.child(k, "."+k, keys[k], segment{kind: reflect.Map, key: k})
+		}
+		return children
+	case elems > 0 && elems <= maxElementColumns && len(keys) == 0:
+		children := make([]column, elems)
+		for i := range children {
+			suffix := "[" + strconv.Itoa(i) + "]"
+			children[i] = c.child(suffix, suffix, elemType, segment{kind: reflect.Slice, elem: i})
+		}
+		return children
+	}
+	return nil
+}
+
+// child derives the column of a nested value of c
+func (c column) child(name, header string, t reflect.Type, seg segment) column {
+	sep := "."
+	if strings.HasPrefix(name, "[") {
+		sep = ""
+	}
+	kind := kindOther
+	if t != nil {
+		kind = kindOfType(t)
+	}
+	return column{
+		name:   c.name + sep + name,
+		field:  c.field + sep + name,
+		header: c.header + header,
+		order:  c.order,
+		kind:   kind,
+		typ:    t,
+		path:   append(append([]segment{}, c.root()...), seg),
+	}
+}
+
+// root is the path to the value of c from the row
+func (c column) root() []segment {
+	switch {
+	case c.path != nil:
+		return c.path
+	case c.index != nil:
+		return []segment{{kind: reflect.Struct, index: c.index, owner: c.owner}}
+	default:
+		return []segment{{kind: reflect.Map, key: c.name}}
+	}
+}
+
+// lookup returns the value of c in row without converting it
+func (c column) lookup(row reflect.Value) reflect.Value {
+	v := row
+	for _, seg := range c.root() {
+		if v = seg.step(v); !v.IsValid() {
+			return v
+		}
+	}
+	return v
+}
+
+// staticType is t unless it is an interface, whose values can be anything
+func staticType(t reflect.Type) reflect.Type {
+	if t.Kind() == reflect.Interface {
+		return nil
+	}
+	return t
+}
+
+func isNestedKind(k reflect.Kind) bool {
+	switch k {
+	case reflect.Interface, reflect.Map, reflect.Slice, reflect.Array, reflect.Struct:
+		return true
+	}
+	return false
+}
+
+// isScalarType reports whether values of t are written as a single cell
+// even though their kind is nested, like times, UUIDs, byte slices and
+// types with their own JSON encoding.
+func isScalarType(t reflect.Type) bool {
+	if t == timeType {
+		return true
+	}
+	for _, m := range []reflect.Type{textMarshalerType, jsonMarshalerType} {
+		if t.Implements(m) || reflect.PointerTo(t).Implements(m) {
+			return true
+		}
+	}
+	return (t.Kind() == reflect.Slice || t.Kind() == reflect.Array) && t.Elem().Kind() == reflect.Uint8
+}
+
+// nestedText renders a value that was not flattened, such as a slice past
+// the flatten depth, as text instead of Go syntax.
+func nestedText(v reflect.Value) any {
+	switch x := v.Interface().(type) {
+	case encoding.TextMarshaler:
+		if text, err := x.MarshalText(); err == nil {
+			return string(text)
+		}
+	case fmt.Stringer:
+		return x.String()
+	}
+	if (v.Kind() == reflect.Map || v.Kind() == reflect.Slice) && v.IsNil() {
+		return nil
+	}
+	if isNestedKind(v.Kind()) {
+		if data, err := json.Marshal(v.Interface()); err == nil {
+			return string(data)
+		}
+	}
+	return v.Interface()
+}
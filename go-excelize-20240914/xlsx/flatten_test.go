@@ -0,0 +1,96 @@
+package xlsx
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+type nestedRow struct {
+	Name  string
+	Data  nestedData
+	Attrs map[string]any
+	Tags  []string
+}
+
+type nestedData struct {
+	X int
+	Y map[string]int
+}
+
+func TestFlattenHeadersAreDeterministic(t *testing.T) {
+	attrs := map[string]any{}
+	for i := range 20 {
+		attrs[fmt.Sprintf("k%02d", 19-i)] = i
+	}
+	tests := []struct {
+		name string
+		data func() Exporter
+	}{
+		{"maps", func() Exporter {
+			return NewXlsx([]map[string]any{attrs, {"z": []int{1, 2}}}, XlsxOptions{})
+		}},
+		{"structs", func() Exporter {
+			return NewXlsx([]nestedRow{{Name: "a", Attrs: attrs, Tags: []string{"x"}}, {Tags: []string{"y", "z"}}}, XlsxOptions{})
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			first := writeRows(t, tt.data(), "")
+			for range 5 {
+				if again := writeRows(t, tt.data(), ""); !reflect.DeepEqual(again[0], first[0]) {
+					t.Fatalf("headers changed between exports:\n%v\n%v", first[0], again[0])
+				}
+			}
+		})
+	}
+}
+
+func TestFlattenDepth(t *testing.T) {
+	data := []nestedRow{
+		{Name: "a", Data: nestedData{X: 1, Y: map[string]int{"z": 1, "b": 2}}, Attrs: map[string]any{"k": 1}, Tags: []string{"t"}},
+		{Name: "b", Tags: []string{"1", "2"}},
+	}
+	tests := []struct {
+		depth int
+		want  [][]string
+	}{
+		{0, [][]string{
+			{"Name", "Data.X", "Data.Y.b", "Data.Y.z", "Attrs.k", "Tags[0]", "Tags[1]"},
+			{"a", "1", "2", "1", "1", "t"},
+			{"b", "0", "", "", "", "1", "2"},
+		}},
+		{1, [][]string{
+			{"Name", "Data.X", "Data.Y", "Attrs.k", "Tags[0]", "Tags[1]"},
+			{"a", "1", `{"b":2,"z":1}`, "1", "t"},
+			{"b", "0", "", "", "1", "2"},
+		}},
+		{-1, [][]string{
+			{"Name", "Data", "Attrs", "Tags"},
+			{"a", `{"X":1,"Y":{"b":2,"z":1}}`, `{"k":1}`, `["t"]`},
+			{"b", `{"X":0,"Y":null}`, "", `["1","2"]`},
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprint(tt.depth), func(t *testing.T) {
+			got := writeRows(t, NewXlsx(data, XlsxOptions{FlattenDepth: tt.depth}), "")
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("rows =\n%q\nwant\n%q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEmptyData(t *testing.T) {
+	got := writeRows(t, NewXlsx([]nestedRow{}, XlsxOptions{}), "")
+	want := [][]string{{"Name", "Data.X", "Data.Y", "Attrs", "Tags"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("struct rows = %q, want %q", got, want)
+	}
+
+	if got := writeRows(t, NewXlsx([]map[string]any{}, XlsxOptions{}), ""); len(got) != 0 {
+		t.Errorf("map rows = %q, want an empty sheet", got)
+	}
+}
@@ -0,0 +1,179 @@
+package xlsx
+
+import (
+	"reflect"
+	"time"
+)
+
+// defaultDateFormat is used for time.Time columns without a format
+const defaultDateFormat = "yyyy-mm-dd hh:mm:ss"
+
+// ColumnOptions overrides the struct tag settings of a single column.
+// Empty fields keep the tag value.
+type ColumnOptions struct {
+	Header    string
+	Width     float64
+	NumFmt    string
+	Hyperlink bool
+	Image     bool
+	Currency  bool
+	// CurrencyCode is the ISO 4217 code of the amounts, e.g. THB
+	CurrencyCode string
+}
+
+// cellKind is the kind of value a column holds, used to pick its default
+// number format.
+type cellKind int
+
+const (
+	kindOther cellKind = iota
+	kindInt
+	kindFloat
+	kindDate
+)
+
+var timeType = reflect.TypeFor[time.Time]()
+
+func kindOfType(t reflect.Type) cellKind {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t == timeType {
+		return kindDate
+	}
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return kindInt
+	case reflect.Float32, reflect.Float64:
+		return kindFloat
+	}
+	return kindOther
+}
+
+// prepareColumns applies the per-column overrides and translations of opt
+// and resolves the number format of every column. Map and interface columns have no static
+// type, so their kind is taken from the first value found in rows. The cached columns are
+// never modified.
+func prepareColumns(columns []column, rows [][]any, opt XlsxOptions) []column {
+	loc := localeOf(opt)
+	prepared := make([]column, len(columns))
+	for i, c := range columns {
+		for key, override := range opt.Columns {
+			if !c.matches(key) {
+				continue
+			}
+			if override.Header != "" {
+				c.header = override.Header
+			}
+			if override.Width > 0 {
+				c.width = override.Width
+			}
+			if override.NumFmt != "" {
+				c.numFmt = override.NumFmt
+			}
+			c.link = c.link || override.Hyperlink
+			c.image = c.image || override.Image
+			c.currency = c.currency || override.Currency
+			if override.CurrencyCode != "" {
+				c.currency, c.currencyCode = true, override.CurrencyCode
+			}
+		}
+		if label := translate(c, opt); label != c.header {
+			c.label, c.header = c.header, label
+		}
+
+		if c.typ == nil && c.kind == kindOther {
+			for _, row := range rows {
+				if row[i] != nil {
+					c.kind = kindOfType(reflect.TypeOf(row[i]))
+					break
+				}
+			}
+		}
+
+		if c.numFmt == "" {
+			switch {
+			case c.currency:
+				c.numFmt = currencyFormat(firstOf(c.currencyCode, opt.Currency), loc)
+			case c.kind == kindDate:
+				c.numFmt = firstOf(opt.DateFormat, loc.dateFormat, defaultDateFormat)
+			case c.kind == kindInt:
+				c.numFmt = firstOf(opt.IntFormat, loc.intFormat)
+			case c.kind == kindFloat:
+				c.numFmt = firstOf(opt.FloatFormat, loc.floatFormat)
+			}
+		}
+		if c.kind == kindDate && opt.BuddhistEra {
+			c.numFmt = buddhistFormat(c.numFmt)
+		}
+		prepared[i] = c
+	}
+	return prepared
+}
+
+// firstOf returns the first of values that is set
+func firstOf(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// normalize turns a field value into the basic Go type excelize writes
+// natively, so named types keep their number or text type and times are
+// shown in loc.
+func normalize(v any, loc *time.Location) any {
+	switch v := v.(type) {
+	case nil, string, bool, int, int64, uint64, float64:
+		return v
+	case time.Time:
+		if v.IsZero() {
+			return nil
+		}
+		return v.In(loc)
+	case []byte:
+		return string(v)
+	}
+
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Pointer || rv.Kind() == reflect.Interface {
+		if rv.IsNil() {
+			return nil
+		}
+		rv = rv.Elem()
+	}
+
+	if rv.Type() == timeType {
+		return normalize(rv.Interface(), loc)
+	}
+	switch rv.Kind() {
+	case reflect.String:
+		return rv.String()
+	case reflect.Bool:
+		return rv.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return rv.Int()
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return rv.Uint()
+	case reflect.Float32, reflect.Float64:
+		return rv.Float()
+	}
+	return nestedText(rv)
+}
+
+func normalizeRow(values []any, loc *time.Location) []any {
+	for i, v := range values {
+		values[i] = normalize(v, loc)
+	}
+	return values
+}
+
+func location(opt XlsxOptions) *time.Location {
+	if opt.Location != nil {
+		return opt.Location
+	}
+	return time.Local
+}
@@ -0,0 +1,40 @@
+package xlsx
+
+import "testing"
+
+type pricedRow struct {
+	Price float64 `xlsx:"Price;currency=THB"`
+	Cost  float64 `xlsx:"Cost;currency"`
+}
+
+func TestCurrencyDoesNotFollowLocale(t *testing.T) {
+	tests := []struct {
+		name     string
+		opt      XlsxOptions
+		wantCost string
+	}{
+		{"no locale", XlsxOptions{}, "#,##0.00"},
+		{"en", XlsxOptions{Locale: "en-US"}, "#,##0.00"},
+		{"th", XlsxOptions{Locale: "th"}, "#,##0.00"},
+		{"options currency", XlsxOptions{Locale: "en", Currency: "usd"}, "[$$]#,##0.00"},
+		{"unknown code", XlsxOptions{Currency: "CHF"}, `#,##0.00 "CHF"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			columns, _ := tabulate([]pricedRow{{1, 2}}, tt.opt)
+			if got, want := columns[0].numFmt, "[$฿]#,##0.00"; got != want {
+				t.Errorf("Price format = %q, want %q", got, want)
+			}
+			if got := columns[1].numFmt; got != tt.wantCost {
+				t.Errorf("Cost format = %q, want %q", got, tt.wantCost)
+			}
+		})
+	}
+}
+
+func TestCurrencyCodeOption(t *testing.T) {
+	columns, _ := tabulate([]pricedRow{{1, 2}}, XlsxOptions{Columns: map[string]ColumnOptions{"Cost": {CurrencyCode: "EUR"}}})
+	if got, want := columns[1].numFmt, "[$€]#,##0.00"; got != want {
+		t.Errorf("Cost format = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,176 @@
+package xlsx
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// Formula adds a computed column whose cells hold an Excel formula, so the
+// value recalculates when users edit the cells it refers to, for example
+//
+//	xlsx.Formula{Header: "Inventory Value", Expr: "{Price}*{Stock}", NumFmt: "#,##0.00"}
+//
+// {Column} refers to the cell of that column in the same row, by header,
+// json name or field name. Formula columns follow the data columns.
+type Formula struct {
+	Header string
+	Expr   string
+	Width  float64
+	NumFmt string
+}
+
+// Summary adds a row below the data that aggregates columns with an Excel
+// function taking a range, such as SUM, AVERAGE, MIN, MAX, COUNT (numbers
+// only) or COUNTA. Label is written to the first column unless that column
+// is aggregated itself.
+//
+//	xlsx.Summary{Label: "Total", Func: "SUM", Columns: []string{"Stock"}}
+type Summary struct {
+	Label   string
+	Func    string
+	Columns []string
+}
+
+// withFormulas appends the formula columns of opt to columns
+func withFormulas(columns []column, opt XlsxOptions) []column {
+	for _, fc := range opt.Formulas {
+		c := column{
+			name:    fc.Header,
+			field:   fc.Header,
+			header:  fc.Header,
+			width:   fc.Width,
+			numFmt:  fc.NumFmt,
+			formula: strings.TrimPrefix(fc.Expr, "="),
+		}
+		if label := translate(c, opt); label != c.header {
+			c.label, c.header = c.header, label
+		}
+		columns = append(columns, c)
+	}
+	return columns
+}
+
+// rowFormula resolves the {Column} references of expr to the cells of row
+func rowFormula(columns []column, expr string, row int) (string, error) {
+	var b strings.Builder
+	for {
+		start := strings.IndexByte(expr, '{')
+		if start < 0 {
+			b.WriteString(expr)
+			return b.String(), nil
+		}
+		end := strings.IndexByte(expr[start:], '}')
+		if end < 0 {
+			return "", fmt.Errorf("unclosed column reference in %q", expr)
+		}
+
+		name := expr[start+1 : start+end]
+		j := columnIndex(columns, name)
+		if j < 0 {
+			return "", fmt.Errorf("unknown column %q in formula", name)
+		}
+		cell, err := excelize.CoordinatesToCellName(j+1, row)
+		if err != nil {
+			return "", err
+		}
+
+		b.WriteString(expr[:start])
+		b.WriteString(cell)
+		expr = expr[start+end+1:]
+	}
+}
+
+func columnIndex(columns []column, name string) int {
+	for j, c := range columns {
+		if c.matches(name) {
+			return j
+		}
+	}
+	return -1
+}
+
+// summaryCells builds the cells of a summary row over the data rows 2 to
+// last. styles holds the style of every column.
+func summaryCells(columns []column, sum Summary, last int, styles []int) ([]excelize.Cell, error) {
+	cells := make([]excelize.Cell, len(columns))
+	for j := range cells {
+		cells[j].StyleID = styles[j]
+	}
+
+	for _, name := range sum.Columns {
+		j := columnIndex(columns, name)
+		if j < 0 {
+			return nil, fmt.Errorf("unknown column %q in summary", name)
+		}
+		col, err := excelize.ColumnNumberToName(j + 1)
+		if err != nil {
+			return nil, err
+		}
+		cells[j].Formula = fmt.Sprintf("%s(%s2:%s%d)", strings.ToUpper(sum.Func), col, col, last)
+	}
+
+	if len(cells) > 0 && cells[0].Formula == "" {
+		cells[0].Value = sum.Label
+	}
+	return cells, nil
+}
+
+// newSummaryStyles registers the bold style of the summary cells of every
+// column, keeping the number format of the column
+func newSummaryStyles(f *excelize.File, columns []column) ([]int, error) {
+	styles := make([]int, len(columns))
+	byFormat := map[string]int{}
+	for j, c := range columns {
+		style, ok := byFormat[c.numFmt]
+		if !ok {
+			s := &excelize.Style{Font: &excelize.Font{Bold: true}}
+			if c.numFmt != "" {
+				numFmt := c.numFmt
+				s.CustomNumFmt = &numFmt
+			}
+			var err error
+			if style, err = f.NewStyle(s); err != nil {
+				return nil, fmt.Errorf("failed to create summary style: %v", err)
+			}
+			byFormat[c.numFmt] = style
+		}
+		styles[j] = style
+	}
+	return styles, nil
+}
+
+// writeSummaries writes the summary rows of an in-memory sheet below its data
+func (f *Xlsx) writeSummaries(s *sheet) error {
+	if len(s.opt.Summaries) == 0 || len(s.columns) == 0 {
+		return nil
+	}
+	styles, err := newSummaryStyles(f.file, s.columns)
+	if err != nil {
+		return err
+	}
+
+	last := max(len(s.rows), 1) + 1
+	for i, sum := range s.opt.Summaries {
+		cells, err := summaryCells(s.columns, sum, last, styles)
+		if err != nil {
+			return err
+		}
+		for j, c := range cells {
+			cell, err := excelize.CoordinatesToCellName(j+1, last+i+1)
+			if err != nil {
+				return err
+			}
+			if c.Formula != "" {
+				if err := f.file.SetCellFormula(s.name, cell, c.Formula); err != nil {
+					return fmt.Errorf("failed to set formula %s: %v", cell, err)
+				}
+			} else if c.Value != nil {
+				f.file.SetCellValue(s.name, cell, c.Value)
+			}
+			f.file.SetCellStyle(s.name, cell, cell, c.StyleID)
+		}
+	}
+	return nil
+}
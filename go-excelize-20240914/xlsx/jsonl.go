@@ -0,0 +1,57 @@
+package xlsx
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+)
+
+// JSONLines exports one JSON object per row, keyed by the column headers
+// in column order.
+type JSONLines struct {
+	columns []column
+	rows    [][]any
+}
+
+func NewJSONLines[T any](tData []T, opt XlsxOptions) *JSONLines {
+	columns, rows := tabulate(tData, opt)
+	return &JSONLines{columns: columns, rows: rows}
+}
+
+func (j *JSONLines) ContentType() string {
+	return formatContentTypes[FormatJSONLines]
+}
+
+func (j *JSONLines) Extension() string {
+	return string(FormatJSONLines)
+}
+
+func (j *JSONLines) Write(w io.Writer) error {
+	keys := make([][]byte, len(j.columns))
+	for i, c := range j.columns {
+		key, err := json.Marshal(c.header)
+		if err != nil {
+			return err
+		}
+		keys[i] = key
+	}
+
+	bw := bufio.NewWriter(w)
+	for _, row := range j.rows {
+		bw.WriteByte('{')
+		for i, v := range row {
+			if i > 0 {
+				bw.WriteByte(',')
+			}
+			value, err := json.Marshal(v)
+			if err != nil {
+				return err
+			}
+			bw.Write(keys[i])
+			bw.WriteByte(':')
+			bw.Write(value)
+		}
+		bw.WriteString("}\n")
+	}
+	return bw.Flush()
+}
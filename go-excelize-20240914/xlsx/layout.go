@@ -0,0 +1,116 @@
+package xlsx
+
+import (
+	"fmt"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// defaultTableStyle is the banded blue style Excel offers first
+const defaultTableStyle = "TableStyleMedium2"
+
+// TableOptions turns the data range of a sheet into an Excel table, which
+// brings its own filter buttons and banded rows.
+type TableOptions struct {
+	// Name must be unique in the workbook; Excel numbers tables if empty
+	Name string
+	// Style is one of Excel's table styles, e.g. "TableStyleLight9"
+	Style           string
+	ShowFirstColumn bool
+	ShowLastColumn  bool
+}
+
+// panes returns the freeze panes for the header row and the first
+// FreezeColumns columns, or nil when nothing is frozen.
+func panes(opt XlsxOptions) (*excelize.Panes, error) {
+	xSplit, ySplit := opt.FreezeColumns, 0
+	if opt.FreezeHeader {
+		ySplit = 1
+	}
+	if xSplit <= 0 && ySplit == 0 {
+		return nil, nil
+	}
+	xSplit = max(xSplit, 0)
+
+	topLeft, err := excelize.CoordinatesToCellName(xSplit+1, ySplit+1)
+	if err != nil {
+		return nil, err
+	}
+
+	activePane := "bottomRight"
+	switch {
+	case xSplit == 0:
+		activePane = "bottomLeft"
+	case ySplit == 0:
+		activePane = "topRight"
+	}
+
+	return &excelize.Panes{
+		Freeze:      true,
+		XSplit:      xSplit,
+		YSplit:      ySplit,
+		TopLeftCell: topLeft,
+		ActivePane:  activePane,
+		Selection: []excelize.Selection{
+			{SQRef: topLeft, ActiveCell: topLeft, Pane: activePane},
+		},
+	}, nil
+}
+
+// dataRange is the range from the header to the last data row. A table
+// needs at least one row below its header, so an empty sheet gets a blank one.
+func dataRange(columns, rows int) (string, error) {
+	lastCell, err := excelize.CoordinatesToCellName(max(columns, 1), max(rows, 1)+1)
+	if err != nil {
+		return "", err
+	}
+	return "A1:" + lastCell, nil
+}
+
+// newTable builds the excelize table for the data range of a sheet
+func newTable(opt *TableOptions, ref string) *excelize.Table {
+	style := opt.Style
+	if style == "" {
+		style = defaultTableStyle
+	}
+	return &excelize.Table{
+		Range:           ref,
+		Name:            opt.Name,
+		StyleName:       style,
+		ShowFirstColumn: opt.ShowFirstColumn,
+		ShowLastColumn:  opt.ShowLastColumn,
+	}
+}
+
+// applyLayout freezes the panes and adds the table or autofilter of an
+// in-memory sheet
+func (f *Xlsx) applyLayout(s *sheet) error {
+	p, err := panes(s.opt)
+	if err != nil {
+		return err
+	}
+	if p != nil {
+		if err := f.file.SetPanes(s.name, p); err != nil {
+			return fmt.Errorf("failed to freeze panes: %v", err)
+		}
+	}
+
+	if len(s.columns) == 0 || (s.opt.Table == nil && !s.opt.AutoFilter) {
+		return nil
+	}
+	ref, err := dataRange(len(s.columns), len(s.rows))
+	if err != nil {
+		return err
+	}
+
+	if s.opt.Table != nil {
+		if err := f.file.AddTable(s.name, newTable(s.opt.Table, ref)); err != nil {
+			return fmt.Errorf("failed to add table: %v", err)
+		}
+		return nil
+	}
+	if err := f.file.AutoFilter(s.name, ref, nil); err != nil {
+		return fmt.Errorf("failed to add autofilter: %v", err)
+	}
+	return nil
+}
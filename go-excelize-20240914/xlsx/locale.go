@@ -0,0 +1,117 @@
+package xlsx
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Catalog holds the translated header labels of each language, keyed by
+// the label, json name or field name of the column, for example
+//
+//	xlsx.Catalog{"th": {"Product Name": "ชื่อสินค้า", "Stock": "คงเหลือ"}}
+type Catalog map[string]map[string]string
+
+// locale holds the number formats and font of a language
+type locale struct {
+	dateFormat  string
+	intFormat   string
+	floatFormat string
+	// font renders the script of the language in Excel on every platform
+	font string
+}
+
+var locales = map[string]locale{
+	"en": {
+		intFormat:   "#,##0",
+		floatFormat: "#,##0.00",
+	},
+	"th": {
+		dateFormat:  "[$-th-TH]dd/mm/yyyy hh:mm:ss",
+		intFormat:   "#,##0",
+		floatFormat: "#,##0.00",
+		font:        "Tahoma",
+	},
+}
+
+// defaultCurrencyFormat is the number part of currency formats
+const defaultCurrencyFormat = "#,##0.00"
+
+// currencySymbols are the symbols of the common ISO 4217 codes. Other codes
+// are shown as the code itself.
+var currencySymbols = map[string]string{
+	"THB": "฿",
+	"USD": "$",
+	"EUR": "€",
+	"GBP": "£",
+	"JPY": "¥",
+}
+
+// currencyFormat formats amounts in the currency of code, e.g. THB. The
+// currency is a property of the data, so the locale only picks the number
+// part and never the symbol.
+func currencyFormat(code string, loc locale) string {
+	number := firstOf(loc.floatFormat, defaultCurrencyFormat)
+	if code == "" {
+		return number
+	}
+	code = strings.ToUpper(code)
+	if symbol, ok := currencySymbols[code]; ok {
+		return "[$" + symbol + "]" + number
+	}
+	return number + ` "` + code + `"`
+}
+
+// yearCode matches the year part of a date format
+var yearCode = regexp.MustCompile(`y+`)
+
+// localeOf returns the settings of opt.Locale, matching "th-TH" to "th".
+// Unknown locales have no settings of their own.
+func localeOf(opt XlsxOptions) locale {
+	lang, _, _ := strings.Cut(strings.ToLower(opt.Locale), "-")
+	return locales[lang]
+}
+
+// Text returns the translation of the first of keys found in the messages
+// of locale, falling back from "th-TH" to "th". It is keys[0] when none is.
+func (c Catalog) Text(locale string, keys ...string) string {
+	if len(keys) == 0 {
+		return ""
+	}
+	lang, _, _ := strings.Cut(strings.ToLower(locale), "-")
+	for _, tag := range []string{locale, lang} {
+		messages, ok := c[tag]
+		if !ok {
+			continue
+		}
+		for _, key := range keys {
+			if text, ok := messages[key]; ok {
+				return text
+			}
+		}
+	}
+	return keys[0]
+}
+
+// translate returns the label of c in the language of opt, or its header
+// when the catalog has no translation.
+func translate(c column, opt XlsxOptions) string {
+	if opt.Locale == "" {
+		return c.header
+	}
+	return opt.Catalog.Text(opt.Locale, c.header, c.name, c.field)
+}
+
+// buddhistFormat switches the years of a date format to the Thai Buddhist
+// era, which Excel shows for the b codes, e.g. 2567 for 2024.
+func buddhistFormat(numFmt string) string {
+	numFmt = yearCode.ReplaceAllStringFunc(numFmt, func(y string) string {
+		if len(y) <= 2 {
+			return "bb"
+		}
+		return "bbbb"
+	})
+	if !strings.HasPrefix(numFmt, "[$-") {
+		numFmt = "[$-th-TH]" + numFmt
+	}
+	return numFmt
+}
@@ -0,0 +1,179 @@
+package xlsx
+
+import (
+	"fmt"
+	"net/http"
+	"path"
+	"strings"
+	"sync"
+
+	"github.com/xuri/excelize/v2"
+)
+
+const (
+	// defaultImageHeight is the row height in points of rows with a thumbnail
+	defaultImageHeight = 48
+	// imageWorkers bounds the number of concurrent image downloads
+	imageWorkers = 10
+	// maxHyperlinkFormula is the longest URL the HYPERLINK function accepts
+	maxHyperlinkFormula = 255
+)
+
+var imageExtensions = map[string]string{
+	"image/png":  ".png",
+	"image/jpeg": ".jpg",
+	"image/gif":  ".gif",
+	"image/bmp":  ".bmp",
+}
+
+var linkStyle = &excelize.Style{
+	Font: &excelize.Font{Color: "0563C1", Underline: "single"},
+}
+
+// writeMedia turns the cells of link columns into hyperlinks and embeds the
+// thumbnails of image columns. Images that cannot be fetched fall back to a
+// hyperlink.
+func (f *Xlsx) writeMedia(s *sheet) error {
+	images := fetchImages(s)
+
+	style := 0
+	for j, c := range s.columns {
+		if !c.link && !c.image {
+			continue
+		}
+
+		for i, row := range s.rows {
+			url, _ := row[j].(string)
+			if url == "" {
+				continue
+			}
+
+			cell, err := excelize.CoordinatesToCellName(j+1, i+2)
+			if err != nil {
+				return err
+			}
+
+			if data, ok := images[url]; ok && c.image {
+				if err := f.addThumbnail(s, cell, i+2, url, data); err == nil {
+					continue
+				}
+			}
+
+			if style == 0 {
+				if style, err = f.file.NewStyle(linkStyle); err != nil {
+					return fmt.Errorf("failed to create link style: %v", err)
+				}
+			}
+			if err := f.file.SetCellHyperLink(s.name, cell, url, "External"); err != nil {
+				return fmt.Errorf("failed to set hyperlink %s: %v", cell, err)
+			}
+			f.file.SetCellStyle(s.name, cell, cell, style)
+		}
+	}
+	return nil
+}
+
+func (f *Xlsx) addThumbnail(s *sheet, cell string, row int, url string, data []byte) error {
+	ext := imageExtension(data, url)
+	if ext == "" {
+		return fmt.Errorf("unsupported image %s", url)
+	}
+
+	height := s.opt.ImageHeight
+	if height <= 0 {
+		height = defaultImageHeight
+	}
+
+	if err := f.file.AddPictureFromBytes(s.name, cell, &excelize.Picture{
+		Extension: ext,
+		File:      data,
+		Format: &excelize.GraphicOptions{
+			AutoFit:         true,
+			LockAspectRatio: true,
+			Positioning:     "oneCell",
+			Hyperlink:       url,
+			HyperlinkType:   "External",
+		},
+	}); err != nil {
+		return err
+	}
+
+	// The picture covers the cell, so drop the URL text underneath
+	f.file.SetCellValue(s.name, cell, nil)
+	return f.file.SetRowHeight(s.name, row, height)
+}
+
+// fetchImages downloads the distinct image URLs of the sheet with bounded
+// concurrency. Failed downloads are left out of the result.
+func fetchImages(s *sheet) map[string][]byte {
+	images := map[string][]byte{}
+	fetch := s.opt.ImageFetcher
+	if fetch == nil {
+		return images
+	}
+
+	urls := map[string]bool{}
+	for j, c := range s.columns {
+		if !c.image {
+			continue
+		}
+		for _, row := range s.rows {
+			if url, _ := row[j].(string); url != "" {
+				urls[url] = true
+			}
+		}
+	}
+
+	var (
+		wg        sync.WaitGroup
+		mu        sync.Mutex
+		semaphore = make(chan struct{}, imageWorkers)
+	)
+	for url := range urls {
+		wg.Add(1)
+		go func(url string) {
+			defer wg.Done()
+			semaphore <- struct{}{}
+			defer func() { <-semaphore }()
+
+			data, err := fetch(url)
+			if err != nil || len(data) == 0 {
+				return
+			}
+			mu.Lock()
+			images[url] = data
+			mu.Unlock()
+		}(url)
+	}
+	wg.Wait()
+	return images
+}
+
+// imageExtension detects the picture type from its content, falling back to
+// the extension of the URL.
+func imageExtension(data []byte, url string) string {
+	if ext, ok := imageExtensions[http.DetectContentType(data)]; ok {
+		return ext
+	}
+	ext := strings.ToLower(path.Ext(url))
+	for _, known := range imageExtensions {
+		if ext == known || ext == ".jpeg" {
+			return ext
+		}
+	}
+	return ""
+}
+
+// hyperlinkCell renders a URL as a HYPERLINK formula for the stream writer,
+// which cannot write hyperlink relations.
+func hyperlinkCell(url string, style int) any {
+	if len(url) > maxHyperlinkFormula {
+		return url
+	}
+	quoted := strings.ReplaceAll(url, `"`, `""`)
+	return excelize.Cell{
+		StyleID: style,
+		Value:   url,
+		Formula: fmt.Sprintf(`HYPERLINK("%s","%s")`, quoted, quoted),
+	}
+}
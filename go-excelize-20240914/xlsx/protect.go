@@ -0,0 +1,105 @@
+package xlsx
+
+import (
+	"fmt"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// Protection locks a sheet against edits. Cells are locked unless their
+// column is listed in Editable, or, when Locked is set instead, unless it is
+// not listed there. For example, to let suppliers update only Stock:
+//
+//	xlsx.Protection{Password: "secret", Editable: []string{"Stock"}}
+//
+// When streaming, only the written cells of editable columns are unlocked.
+type Protection struct {
+	// Password is needed to unprotect the sheet in Excel
+	Password string
+	Editable []string
+	Locked   []string
+	// AllowSort and AllowFilter keep sorting and filtering usable
+	AllowSort   bool
+	AllowFilter bool
+}
+
+// editable reports whether the cells of c stay unlocked on a protected sheet
+func (p *Protection) editable(c column) bool {
+	if p == nil {
+		return false
+	}
+	if len(p.Locked) > 0 {
+		return !matchesAny(c, p.Locked)
+	}
+	return matchesAny(c, p.Editable)
+}
+
+func matchesAny(c column, names []string) bool {
+	for _, name := range names {
+		if c.matches(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// protect turns on the protection of a sheet. With the stream writer it
+// must run before Flush.
+func protect(f *excelize.File, sheetName string, p *Protection) error {
+	if p == nil {
+		return nil
+	}
+	err := f.ProtectSheet(sheetName, &excelize.SheetProtectionOptions{
+		AlgorithmName:       "SHA-512",
+		Password:            p.Password,
+		SelectLockedCells:   true,
+		SelectUnlockedCells: true,
+		FormatColumns:       true,
+		Sort:                p.AllowSort,
+		AutoFilter:          p.AllowFilter,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to protect sheet %s: %v", sheetName, err)
+	}
+	return nil
+}
+
+// newDataStyle registers the style of the data cells of a column, carrying
+// its number format and whether it stays editable. It is 0 when the cells
+// need neither.
+func newDataStyle(f *excelize.File, c column, unlocked bool) (int, error) {
+	if c.numFmt == "" && !unlocked {
+		return 0, nil
+	}
+	style := &excelize.Style{}
+	if c.numFmt != "" {
+		numFmt := c.numFmt
+		style.CustomNumFmt = &numFmt
+	}
+	if unlocked {
+		style.Protection = &excelize.Protection{Locked: false}
+	}
+	return f.NewStyle(style)
+}
+
+// unlockColumns gives the editable columns of an in-memory sheet an unlocked
+// column style, so rows users add below the data stay editable too
+func (f *Xlsx) unlockColumns(s *sheet) error {
+	for j, c := range s.columns {
+		if !s.opt.Protection.editable(c) {
+			continue
+		}
+		style, err := newDataStyle(f.file, c, true)
+		if err != nil {
+			return fmt.Errorf("failed to create unlocked style: %v", err)
+		}
+		col, err := excelize.ColumnNumberToName(j + 1)
+		if err != nil {
+			return err
+		}
+		if err := f.file.SetColStyle(s.name, col, style); err != nil {
+			return fmt.Errorf("failed to unlock column %s: %v", c.header, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,331 @@
+package xlsx
+
+import (
+	"encoding"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// ErrRequired is reported for an empty cell in a `required` column.
+var ErrRequired = errors.New("value is required")
+
+// dateLayouts are tried in order for date cells stored as text.
+var dateLayouts = []string{
+	time.RFC3339,
+	"2006-01-02 15:04:05",
+	"2006-01-02",
+	"02/01/2006 15:04:05",
+	"02/01/2006",
+}
+
+type ReadOptions struct {
+	// Sheet defaults to the first sheet of the workbook.
+	Sheet string
+	// HeaderRow is the 1-based row holding the headers, 1 by default.
+	HeaderRow int
+}
+
+// CellError describes a cell whose value could not be read into its field.
+// Column and Header are empty for errors that concern the whole row, such as
+// a failed Validate.
+type CellError struct {
+	Sheet  string
+	Row    int
+	Column string
+	Header string
+	Err    error
+}
+
+func (e *CellError) Error() string {
+	if e.Column == "" {
+		return fmt.Sprintf("%s row %d: %v", e.Sheet, e.Row, e.Err)
+	}
+	return fmt.Sprintf("%s!%s%d (%s): %v", e.Sheet, e.Column, e.Row, e.Header, e.Err)
+}
+
+func (e *CellError) Unwrap() error {
+	return e.Err
+}
+
+// Validator is implemented by row types that check themselves after all
+// cells have been converted.
+type Validator interface {
+	Validate() error
+}
+
+// Row is one data line of the sheet. Line is the row number as shown in
+// Excel, so errors can be traced back to the uploaded file.
+type Row[T any] struct {
+	Line   int
+	Value  T
+	Errors []*CellError
+}
+
+func (r Row[T]) Valid() bool {
+	return len(r.Errors) == 0
+}
+
+// Read parses a sheet into values of the struct type T. Header cells are
+// matched against the xlsx label, json name or field name of each column,
+// ignoring case. Conversion and validation problems are collected per row;
+// the returned error is only set when the workbook itself cannot be read.
+func Read[T any](r io.Reader, opt ReadOptions) ([]Row[T], error) {
+	t := reflect.TypeFor[T]()
+	if t.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("xlsx: Read needs a struct type, got %s", t)
+	}
+
+	f, err := excelize.OpenReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open workbook: %v", err)
+	}
+	defer f.Close()
+
+	sheet := opt.Sheet
+	if sheet == "" {
+		sheet = f.GetSheetName(0)
+	}
+	headerRow := opt.HeaderRow
+	if headerRow <= 0 {
+		headerRow = 1
+	}
+
+	rows, err := f.Rows(sheet)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read sheet %s: %v", sheet, err)
+	}
+	defer rows.Close()
+
+	var (
+		result  []Row[T]
+		mapping []*column
+		line    int
+	)
+	for rows.Next() {
+		line++
+		cells, err := rows.Columns(excelize.Options{RawCellValue: true})
+		if err != nil {
+			return nil, fmt.Errorf("failed to read row %d: %v", line, err)
+		}
+
+		if line < headerRow {
+			continue
+		}
+		if line == headerRow {
+			if mapping, err = mapHeaders(columnsOf(t), cells); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if isBlankRow(cells) {
+			continue
+		}
+
+		result = append(result, readRow[T](sheet, line, cells, mapping))
+	}
+	if err := rows.Error(); err != nil {
+		return nil, fmt.Errorf("failed to read sheet %s: %v", sheet, err)
+	}
+	if mapping == nil {
+		return nil, fmt.Errorf("sheet %s has no header row %d", sheet, headerRow)
+	}
+	return result, nil
+}
+
+// mapHeaders resolves each header cell to the column it fills, or nil when
+// the header is unknown.
+func mapHeaders(columns []column, headers []string) ([]*column, error) {
+	mapping := make([]*column, len(headers))
+	found := make([]bool, len(columns))
+	for i, header := range headers {
+		header = strings.TrimSpace(header)
+		for j := range columns {
+			c := &columns[j]
+			if strings.EqualFold(header, c.header) || strings.EqualFold(header, c.name) || strings.EqualFold(header, c.field) {
+				mapping[i] = c
+				found[j] = true
+				break
+			}
+		}
+	}
+
+	for j, c := range columns {
+		if c.required && !found[j] {
+			return nil, fmt.Errorf("missing required column %q", c.header)
+		}
+	}
+	return mapping, nil
+}
+
+func readRow[T any](sheet string, line int, cells []string, mapping []*column) Row[T] {
+	row := Row[T]{Line: line}
+	v := reflect.ValueOf(&row.Value).Elem()
+
+	for i, c := range mapping {
+		if c == nil {
+			continue
+		}
+
+		raw := ""
+		if i < len(cells) {
+			raw = strings.TrimSpace(cells[i])
+		}
+
+		var err error
+		if raw == "" {
+			if c.required {
+				err = ErrRequired
+			}
+		} else {
+			var fv reflect.Value
+			if fv, err = fieldByIndex(v, c.index); err == nil {
+				err = setField(fv, raw)
+			}
+		}
+
+		if err != nil {
+			colName, _ := excelize.ColumnNumberToName(i + 1)
+			row.Errors = append(row.Errors, &CellError{
+				Sheet:  sheet,
+				Row:    line,
+				Column: colName,
+				Header: c.header,
+				Err:    err,
+			})
+		}
+	}
+
+	if row.Valid() {
+		if validator, ok := any(&row.Value).(Validator); ok {
+			if err := validator.Validate(); err != nil {
+				row.Errors = append(row.Errors, &CellError{Sheet: sheet, Row: line, Err: err})
+			}
+		}
+	}
+	return row
+}
+
+// fieldByIndex is like reflect.Value.FieldByIndex but allocates nil
+// embedded pointers on the way. Like encoding/json it fails on a nil
+// pointer to an unexported struct, which reflect cannot set.
+func fieldByIndex(v reflect.Value, index []int) (reflect.Value, error) {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Pointer {
+			if v.IsNil() {
+				if !v.CanSet() {
+					return reflect.Value{}, fmt.Errorf("cannot set embedded pointer to unexported struct %s", v.Type().Elem())
+				}
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v, nil
+}
+
+func setField(fv reflect.Value, raw string) error {
+	if fv.Kind() == reflect.Pointer {
+		ptr := reflect.New(fv.Type().Elem())
+		if err := setField(ptr.Elem(), raw); err != nil {
+			return err
+		}
+		fv.Set(ptr)
+		return nil
+	}
+
+	if fv.Type() == reflect.TypeFor[time.Time]() {
+		t, err := parseTime(raw)
+		if err != nil {
+			return err
+		}
+		fv.Set(reflect.ValueOf(t))
+		return nil
+	}
+
+	if u, ok := fv.Addr().Interface().(encoding.TextUnmarshaler); ok {
+		return u.UnmarshalText([]byte(raw))
+	}
+
+	switch fv.Kind() {
+	case reflect.String:
+		fv.SetString(raw)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return fmt.Errorf("invalid boolean %q", raw)
+		}
+		fv.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := parseNumber(raw)
+		if err != nil || n != float64(int64(n)) {
+			return fmt.Errorf("invalid integer %q", raw)
+		}
+		if fv.OverflowInt(int64(n)) {
+			return fmt.Errorf("integer %q out of range", raw)
+		}
+		fv.SetInt(int64(n))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := parseNumber(raw)
+		if err != nil || n < 0 || n != float64(uint64(n)) {
+			return fmt.Errorf("invalid unsigned integer %q", raw)
+		}
+		if fv.OverflowUint(uint64(n)) {
+			return fmt.Errorf("integer %q out of range", raw)
+		}
+		fv.SetUint(uint64(n))
+	case reflect.Float32, reflect.Float64:
+		n, err := parseNumber(raw)
+		if err != nil {
+			return fmt.Errorf("invalid number %q", raw)
+		}
+		if fv.OverflowFloat(n) {
+			return fmt.Errorf("number %q out of range", raw)
+		}
+		fv.SetFloat(n)
+	default:
+		return fmt.Errorf("unsupported field type %s", fv.Type())
+	}
+	return nil
+}
+
+// parseNumber accepts raw numeric cells as well as numbers typed as text
+// with thousands separators.
+func parseNumber(raw string) (float64, error) {
+	return strconv.ParseFloat(strings.ReplaceAll(raw, ",", ""), 64)
+}
+
+// parseTime accepts Excel date serials and the common text layouts. The
+// result is interpreted in the local time zone.
+func parseTime(raw string) (time.Time, error) {
+	if serial, err := strconv.ParseFloat(raw, 64); err == nil {
+		t, err := excelize.ExcelDateToTime(serial, false)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid date %q", raw)
+		}
+		return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), time.Local), nil
+	}
+
+	for _, layout := range dateLayouts {
+		if t, err := time.ParseInLocation(layout, raw, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid date %q", raw)
+}
+
+func isBlankRow(cells []string) bool {
+	for _, cell := range cells {
+		if strings.TrimSpace(cell) != "" {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,184 @@
+package xlsx
+
+import (
+	"bytes"
+	"errors"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+type audit struct {
+	CreatedBy string `xlsx:"Created By"`
+}
+
+type readProduct struct {
+	*audit
+	ID       int       `json:"id" xlsx:"ID;required"`
+	Name     string    `json:"name" xlsx:"Name"`
+	Price    float64   `json:"price" xlsx:"Price"`
+	InStock  bool      `json:"in_stock" xlsx:"In Stock"`
+	Released time.Time `json:"released" xlsx:"Released"`
+	Rating   *int      `json:"rating" xlsx:"Rating"`
+}
+
+// workbook writes rows of cells under their headers with NewXlsx. Each row
+// is a map from header to value, so tests can leave cells out or write
+// text into number columns.
+func workbook(t *testing.T, headers []string, rows ...map[string]any) *bytes.Reader {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := NewXlsx(rows, XlsxOptions{Headers: headers}).Write(&buf); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	return bytes.NewReader(buf.Bytes())
+}
+
+func TestRead(t *testing.T) {
+	released := time.Date(2024, 9, 14, 10, 30, 0, 0, time.Local)
+	five := 5
+
+	tests := []struct {
+		name    string
+		headers []string
+		rows    []map[string]any
+		want    []readProduct
+		errs    [][]string
+	}{
+		{
+			name:    "converts cells",
+			headers: []string{"ID", "Name", "Price", "In Stock", "Released", "Rating"},
+			rows: []map[string]any{
+				{"ID": 1, "Name": "Pen", "Price": 12.5, "In Stock": true, "Released": released, "Rating": 5},
+			},
+			want: []readProduct{{ID: 1, Name: "Pen", Price: 12.5, InStock: true, Released: released, Rating: &five}},
+			errs: [][]string{nil},
+		},
+		{
+			name:    "matches headers by json and field name",
+			headers: []string{"name", "ID", "INSTOCK"},
+			rows: []map[string]any{
+				{"ID": 2, "name": "Ink", "INSTOCK": "false"},
+			},
+			want: []readProduct{{ID: 2, Name: "Ink"}},
+			errs: [][]string{nil},
+		},
+		{
+			name:    "parses numbers and dates typed as text",
+			headers: []string{"ID", "Price", "Released"},
+			rows: []map[string]any{
+				{"ID": "3", "Price": "1,250.75", "Released": "2024-09-14 10:30:00"},
+			},
+			want: []readProduct{{ID: 3, Price: 1250.75, Released: released}},
+			errs: [][]string{nil},
+		},
+		{
+			name:    "reports errors per row",
+			headers: []string{"ID", "Name", "Price", "Rating"},
+			rows: []map[string]any{
+				{"Name": "No ID", "Price": "cheap"},
+				{"ID": 4, "Name": "Ok"},
+				{"ID": 1.5, "Rating": "high"},
+			},
+			want: []readProduct{{Name: "No ID"}, {ID: 4, Name: "Ok"}, {}},
+			errs: [][]string{
+				{"A2", "C2"},
+				nil,
+				{"A4", "D4"},
+			},
+		},
+		{
+			name:    "skips blank rows and unknown headers",
+			headers: []string{"ID", "Colour"},
+			rows: []map[string]any{
+				{"ID": 5, "Colour": "red"},
+				{},
+				{"ID": 6},
+			},
+			want: []readProduct{{ID: 5}, {ID: 6}},
+			errs: [][]string{nil, nil},
+		},
+		{
+			name:    "cannot fill a nil unexported embedded pointer",
+			headers: []string{"ID", "Created By"},
+			rows: []map[string]any{
+				{"ID": 7, "Created By": "admin"},
+			},
+			want: []readProduct{{ID: 7}},
+			errs: [][]string{{"B2"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rows, err := Read[readProduct](workbook(t, tt.headers, tt.rows...), ReadOptions{})
+			if err != nil {
+				t.Fatalf("Read: %v", err)
+			}
+			if len(rows) != len(tt.want) {
+				t.Fatalf("got %d rows, want %d", len(rows), len(tt.want))
+			}
+
+			for i, row := range rows {
+				got, want := row.Value, tt.want[i]
+				if got.ID != want.ID || got.Name != want.Name || got.Price != want.Price ||
+					got.InStock != want.InStock || !got.Released.Equal(want.Released) ||
+					(got.Rating == nil) != (want.Rating == nil) || got.Rating != nil && *got.Rating != *want.Rating {
+					t.Errorf("row %d = %+v, want %+v", i, got, want)
+				}
+
+				var cells []string
+				for _, e := range row.Errors {
+					cells = append(cells, e.Column+strconv.Itoa(e.Row))
+				}
+				if strings.Join(cells, ",") != strings.Join(tt.errs[i], ",") {
+					t.Errorf("row %d errors = %v, want cells %v", i, row.Errors, tt.errs[i])
+				}
+			}
+		})
+	}
+}
+
+func TestReadRequiredColumn(t *testing.T) {
+	_, err := Read[readProduct](workbook(t, []string{"Name"}, map[string]any{"Name": "Pen"}), ReadOptions{})
+	if err == nil || !strings.Contains(err.Error(), `"ID"`) {
+		t.Fatalf("Read without ID column: err = %v", err)
+	}
+}
+
+func TestReadRequiredCell(t *testing.T) {
+	rows, err := Read[readProduct](workbook(t, []string{"ID", "Name"}, map[string]any{"Name": "Pen"}), ReadOptions{})
+	if err != nil {
+		t.Fatalf("Read: %v", err)
+	}
+	if len(rows) != 1 || len(rows[0].Errors) != 1 || !errors.Is(rows[0].Errors[0], ErrRequired) {
+		t.Fatalf("rows = %+v, want one ErrRequired", rows)
+	}
+}
+
+func TestReadRoundTrip(t *testing.T) {
+	type item struct {
+		SKU   string  `xlsx:"SKU"`
+		Qty   int     `xlsx:"Qty"`
+		Price float64 `xlsx:"Price"`
+	}
+	items := []item{{"A-1", 3, 9.99}, {"B-2", 0, 120}}
+
+	var buf bytes.Buffer
+	if err := NewXlsx(items, XlsxOptions{}).Write(&buf); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	rows, err := Read[item](&buf, ReadOptions{})
+	if err != nil {
+		t.Fatalf("Read: %v", err)
+	}
+	if len(rows) != len(items) {
+		t.Fatalf("got %d rows, want %d", len(rows), len(items))
+	}
+	for i, row := range rows {
+		if !row.Valid() || row.Value != items[i] {
+			t.Errorf("row %d = %+v, want %+v", i, row, items[i])
+		}
+	}
+}
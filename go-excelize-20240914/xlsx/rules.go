@@ -0,0 +1,208 @@
+package xlsx
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// defaultHighlight is the light red fill of conditional formats without a
+// color of their own
+const defaultHighlight = "FFC7CE"
+
+// ConditionalFormat highlights the cells of Column that compare to Value,
+// for example "highlight Stock < 10 in red":
+//
+//	xlsx.ConditionalFormat{Column: "Stock", Operator: "<", Value: 10, Fill: "FFC7CE"}
+//
+// Operator is one of <, <=, >, >=, ==, != or between/not between, which also
+// use MaxValue. String values are compared as text.
+type ConditionalFormat struct {
+	Column    string
+	Operator  string
+	Value     any
+	MaxValue  any
+	Fill      string
+	FontColor string
+}
+
+// Validation restricts what can be typed into the cells of Column, for
+// example "Price must be > 0":
+//
+//	xlsx.Validation{Column: "Price", Operator: ">", Value: 0}
+//
+// Type is whole, decimal, date, textLength or list and defaults to list when
+// List is set, or else to the type of the column. Rules apply to the data
+// rows of the column, not to the summary rows below them.
+type Validation struct {
+	Column   string
+	Type     string
+	Operator string
+	Value    any
+	MaxValue any
+	List     []string
+	// Message is shown when a value is rejected
+	Message    string
+	AllowBlank bool
+}
+
+var validationTypes = map[string]excelize.DataValidationType{
+	"whole":      excelize.DataValidationTypeWhole,
+	"decimal":    excelize.DataValidationTypeDecimal,
+	"date":       excelize.DataValidationTypeDate,
+	"textLength": excelize.DataValidationTypeTextLength,
+	"list":       excelize.DataValidationTypeList,
+}
+
+var validationOperators = map[string]excelize.DataValidationOperator{
+	"<":           excelize.DataValidationOperatorLessThan,
+	"<=":          excelize.DataValidationOperatorLessThanOrEqual,
+	">":           excelize.DataValidationOperatorGreaterThan,
+	">=":          excelize.DataValidationOperatorGreaterThanOrEqual,
+	"=":           excelize.DataValidationOperatorEqual,
+	"==":          excelize.DataValidationOperatorEqual,
+	"!=":          excelize.DataValidationOperatorNotEqual,
+	"<>":          excelize.DataValidationOperatorNotEqual,
+	"between":     excelize.DataValidationOperatorBetween,
+	"not between": excelize.DataValidationOperatorNotBetween,
+}
+
+// applyRules adds the conditional formats and data validations of opt to
+// the rows of the sheet, leaving out the summary rows below them. With the
+// stream writer it must run before Flush.
+func applyRules(f *excelize.File, sheetName string, columns []column, rows int, opt XlsxOptions) error {
+	for _, rule := range opt.ConditionalFormats {
+		ref, _, err := columnRange(columns, rule.Column, rows)
+		if err != nil {
+			return err
+		}
+
+		fill := rule.Fill
+		if fill == "" {
+			fill = defaultHighlight
+		}
+		style := &excelize.Style{
+			Fill: excelize.Fill{Type: "pattern", Pattern: 1, Color: []string{fill}},
+		}
+		if rule.FontColor != "" {
+			style.Font = &excelize.Font{Color: rule.FontColor}
+		}
+		format, err := f.NewConditionalStyle(style)
+		if err != nil {
+			return fmt.Errorf("failed to create conditional style: %v", err)
+		}
+
+		cf := excelize.ConditionalFormatOptions{
+			Type:     "cell",
+			Criteria: rule.Operator,
+			Format:   format,
+		}
+		if strings.HasSuffix(rule.Operator, "between") {
+			cf.MinValue, cf.MaxValue = ruleValue(rule.Value), ruleValue(rule.MaxValue)
+		} else {
+			cf.Value = ruleValue(rule.Value)
+		}
+		if err := f.SetConditionalFormat(sheetName, ref, []excelize.ConditionalFormatOptions{cf}); err != nil {
+			return fmt.Errorf("failed to set conditional format on %s: %v", rule.Column, err)
+		}
+	}
+
+	for _, rule := range opt.Validations {
+		ref, c, err := columnRange(columns, rule.Column, rows)
+		if err != nil {
+			return err
+		}
+
+		dv := excelize.NewDataValidation(rule.AllowBlank)
+		dv.SetSqref(ref)
+		if err := setValidation(dv, rule, c); err != nil {
+			return fmt.Errorf("invalid validation on %s: %v", rule.Column, err)
+		}
+		if rule.Message != "" {
+			dv.SetError(excelize.DataValidationErrorStyleStop, rule.Column, rule.Message)
+		}
+		if err := f.AddDataValidation(sheetName, dv); err != nil {
+			return fmt.Errorf("failed to add validation on %s: %v", rule.Column, err)
+		}
+	}
+	return nil
+}
+
+func setValidation(dv *excelize.DataValidation, rule Validation, c column) error {
+	typ := rule.Type
+	if typ == "" {
+		switch {
+		case len(rule.List) > 0:
+			typ = "list"
+		case c.kind == kindInt:
+			typ = "whole"
+		case c.kind == kindDate:
+			typ = "date"
+		default:
+			typ = "decimal"
+		}
+	}
+
+	if typ == "list" {
+		return dv.SetDropList(rule.List)
+	}
+
+	t, ok := validationTypes[typ]
+	if !ok {
+		return fmt.Errorf("unknown type %q", typ)
+	}
+	o, ok := validationOperators[rule.Operator]
+	if !ok {
+		return fmt.Errorf("unknown operator %q", rule.Operator)
+	}
+
+	upper := ""
+	if rule.MaxValue != nil {
+		upper = ruleValue(rule.MaxValue)
+	}
+	return dv.SetRange(ruleValue(rule.Value), upper, t, o)
+}
+
+// columnRange returns the cell range of the data rows of a column, which
+// covers at least one row below the header like the summaries do
+func columnRange(columns []column, name string, rows int) (string, column, error) {
+	for j, c := range columns {
+		if !c.matches(name) {
+			continue
+		}
+		col, err := excelize.ColumnNumberToName(j + 1)
+		if err != nil {
+			return "", c, err
+		}
+		return fmt.Sprintf("%s2:%s%d", col, col, max(rows, 1)+1), c, nil
+	}
+	return "", column{}, fmt.Errorf("unknown column %q", name)
+}
+
+// ruleValue renders a rule operand as an Excel formula value
+func ruleValue(v any) string {
+	switch v := normalize(v, time.Local).(type) {
+	case nil:
+		return ""
+	case string:
+		if strings.HasPrefix(v, "=") {
+			return strings.TrimPrefix(v, "=")
+		}
+		return `"` + strings.ReplaceAll(v, `"`, `""`) + `"`
+	case time.Time:
+		return strconv.FormatFloat(excelSerial(v), 'f', -1, 64)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// excelSerial converts a time to an Excel serial date using its wall clock
+func excelSerial(t time.Time) float64 {
+	epoch := time.Date(1899, 12, 30, 0, 0, 0, 0, t.Location())
+	return t.Sub(epoch).Hours() / 24
+}
@@ -0,0 +1,296 @@
+package xlsx
+
+import (
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// defaultColWidth is used for columns without an explicit width.
+const defaultColWidth = 20
+
+// column describes how a single field is exported. It is built from the
+// `xlsx` struct tag, which has the form
+//
+//	`xlsx:"Unit Price;order=4;width=15;format=#,##0.00;omitempty"`
+//
+// The first part is the header label and falls back to the json name, then
+// the field name. Options are separated by semicolons so number formats can
+// contain commas. `xlsx:"-"` leaves the field out of the export, and
+// `required` makes Read reject rows where the cell is empty. `link` renders
+// the value as a clickable hyperlink and `image` embeds the picture the URL
+// points to. `currency` formats the value as money in XlsxOptions.Currency,
+// and `currency=THB` in the given ISO 4217 currency.
+type column struct {
+	name      string
+	field     string
+	header    string
+	order     int
+	width     float64
+	numFmt    string
+	omitEmpty bool
+	required  bool
+	link      bool
+	image     bool
+	currency  bool
+	// currencyCode is the ISO 4217 code of a currency column
+	currencyCode string
+	kind         cellKind
+	index        []int
+	// owner is the struct type index belongs to
+	owner reflect.Type
+	// formula is the expression of a Formula column
+	formula string
+	// typ is the static type of the values, nil when only known from the data
+	typ reflect.Type
+	// path leads to the value of a flattened nested column
+	path []segment
+	// label is the header before translation, which rules keep referring to
+	label string
+}
+
+var columnCache sync.Map // map[reflect.Type][]column
+
+// columnsOf returns the exported columns of a struct type in tag order,
+// reading the tags only once per type.
+func columnsOf(t reflect.Type) []column {
+	if cached, ok := columnCache.Load(t); ok {
+		return cached.([]column)
+	}
+
+	columns := parseColumns(t, nil)
+	for i := range columns {
+		columns[i].owner = t
+	}
+	sort.SliceStable(columns, func(i, j int) bool {
+		return columns[i].order < columns[j].order
+	})
+
+	cached, _ := columnCache.LoadOrStore(t, columns)
+	return cached.([]column)
+}
+
+func parseColumns(t reflect.Type, index []int) []column {
+	var columns []column
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		fieldIndex := append(append([]int{}, index...), i)
+
+		tag := sf.Tag.Get("xlsx")
+		if tag == "-" {
+			continue
+		}
+
+		if sf.Anonymous && tag == "" {
+			ft := sf.Type
+			if ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				columns = append(columns, parseColumns(ft, fieldIndex)...)
+				continue
+			}
+		}
+
+		if !sf.IsExported() {
+			continue
+		}
+
+		columns = append(columns, parseColumn(sf, tag, fieldIndex))
+	}
+	return columns
+}
+
+func parseColumn(sf reflect.StructField, tag string, index []int) column {
+	name := sf.Name
+	if jsonName, _, _ := strings.Cut(sf.Tag.Get("json"), ","); jsonName != "" && jsonName != "-" {
+		name = jsonName
+	}
+
+	c := column{
+		name:   name,
+		field:  sf.Name,
+		header: name,
+		order:  math.MaxInt,
+		kind:   kindOfType(sf.Type),
+		typ:    sf.Type,
+		index:  index,
+	}
+
+	parts := strings.Split(tag, ";")
+	if label := strings.TrimSpace(parts[0]); label != "" {
+		c.header = label
+	}
+
+	for _, part := range parts[1:] {
+		key, value, _ := strings.Cut(strings.TrimSpace(part), "=")
+		switch key {
+		case "order":
+			if n, err := strconv.Atoi(value); err == nil {
+				c.order = n
+			}
+		case "width":
+			if n, err := strconv.ParseFloat(value, 64); err == nil {
+				c.width = n
+			}
+		case "format":
+			c.numFmt = value
+		case "omitempty":
+			c.omitEmpty = true
+		case "required":
+			c.required = true
+		case "link":
+			c.link = true
+		case "image":
+			c.image = true
+		case "currency":
+			c.currency, c.currencyCode = true, value
+		}
+	}
+	return c
+}
+
+// matches reports whether a header given in XlsxOptions refers to this
+// column, either by its label, its json name or its Go field name.
+func (c column) matches(header string) bool {
+	return header == c.header || header == c.name || header == c.field || (c.label != "" && header == c.label)
+}
+
+// nestedIn reports whether c was flattened from the value named header
+func (c column) nestedIn(header string) bool {
+	for _, name := range []string{c.header, c.name, c.field} {
+		if strings.HasPrefix(name, header+".") || strings.HasPrefix(name, header+"[") {
+			return true
+		}
+	}
+	return false
+}
+
+func (c column) colWidth() float64 {
+	if c.width > 0 {
+		return c.width
+	}
+	return defaultColWidth
+}
+
+// value extracts the cell value of this column from a struct or map row.
+func (c column) value(v reflect.Value) any {
+	field := c.lookup(v)
+	if !field.IsValid() {
+		return nil
+	}
+	if c.omitEmpty && field.IsZero() {
+		return nil
+	}
+	return field.Interface()
+}
+
+// schemaOf returns the columns for rows of type t. Struct columns come from
+// the type itself in declaration order. Map columns are the keys found in
+// the sample rows in sorted order, as maps have no order of their own.
+// Rows of interface type take the columns of the first row; rows of another
+// type leave those columns empty.
+func schemaOf(t reflect.Type, samples []reflect.Value) []column {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		return columnsOf(t)
+	case reflect.Map:
+		if t.Key().Kind() != reflect.String {
+			return nil
+		}
+		keys := map[string]bool{}
+		for _, sample := range samples {
+			sample = indirect(sample)
+			if !sample.IsValid() || sample.Kind() != reflect.Map {
+				continue
+			}
+			for _, k := range sample.MapKeys() {
+				keys[k.String()] = true
+			}
+		}
+		names := make([]string, 0, len(keys))
+		for k := range keys {
+			names = append(names, k)
+		}
+		sort.Strings(names)
+
+		columns := make([]column, len(names))
+		for i, k := range names {
+			columns[i] = column{name: k, field: k, header: k, typ: staticType(t.Elem())}
+		}
+		return columns
+	case reflect.Interface:
+		for _, sample := range samples {
+			if sample = indirect(sample); sample.IsValid() {
+				return schemaOf(sample.Type(), samples)
+			}
+		}
+	}
+	return nil
+}
+
+// selectColumns narrows and orders the columns to the given headers. The
+// header of a flattened value selects all of its nested columns, and a header
+// without a matching column is kept as an empty column.
+func selectColumns(columns []column, headers []string) []column {
+	if len(headers) == 0 {
+		return columns
+	}
+
+	selected := make([]column, 0, len(headers))
+	for _, header := range headers {
+		found := false
+		for _, c := range columns {
+			if c.matches(header) {
+				selected = append(selected, c)
+				found = true
+				break
+			}
+		}
+		for _, c := range columns {
+			if !found && c.nestedIn(header) {
+				selected = append(selected, c)
+			}
+		}
+		if len(selected) > 0 && selected[len(selected)-1].nestedIn(header) {
+			found = true
+		}
+		if !found {
+			selected = append(selected, column{name: header, field: header, header: header})
+		}
+	}
+	return selected
+}
+
+// tabulate converts the data into columns and row values in column order,
+// keeping the Go type of every value.
+func tabulate[T any](data []T, opt XlsxOptions) ([]column, [][]any) {
+	values := make([]reflect.Value, len(data))
+	for i := range data {
+		values[i] = reflect.ValueOf(&data[i]).Elem()
+	}
+	columns := flatten(schemaOf(reflect.TypeFor[T](), values), values, flattenDepth(opt))
+	columns = selectColumns(columns, opt.Headers)
+
+	loc := location(opt)
+	rows := make([][]any, len(data))
+	for i := range data {
+		rows[i] = normalizeRow(rowValues(columns, values[i]), loc)
+	}
+	return prepareColumns(columns, rows, opt), rows
+}
+
+func rowValues(columns []column, v reflect.Value) []any {
+	values := make([]any, len(columns))
+	for i, c := range columns {
+		values[i] = c.value(v)
+	}
+	return values
+}
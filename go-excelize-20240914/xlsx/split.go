@@ -0,0 +1,98 @@
+package xlsx
+
+import (
+	"archive/zip"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// maxSheetRows is the number of data rows that fit on one sheet: MaxRows,
+// or what Excel's row limit leaves after the header and summary rows.
+func maxSheetRows(opt XlsxOptions) int {
+	limit := excelize.TotalRows - 1 - len(opt.Summaries)
+	if opt.MaxRows > 0 && opt.MaxRows < limit {
+		return opt.MaxRows
+	}
+	return limit
+}
+
+// partName names the sheet holding the part-th rows of a sheet that rolled
+// over: "Products", "Products (2)", "Products (3)"...
+func partName(name string, part int) string {
+	if part == 0 {
+		return name
+	}
+	suffix := fmt.Sprintf(" (%d)", part+1)
+	for utf8.RuneCountInString(name)+len(suffix) > excelize.MaxSheetNameLength {
+		_, size := utf8.DecodeLastRuneInString(name)
+		name = name[:len(name)-size]
+	}
+	return name + suffix
+}
+
+// partOptions are the options of a rolled over part of a sheet. Table names
+// must be unique, and dashboards only summarize the first part.
+func partOptions(opt XlsxOptions, part int) XlsxOptions {
+	if part == 0 {
+		return opt
+	}
+	if opt.Table != nil && opt.Table.Name != "" {
+		table := *opt.Table
+		table.Name = fmt.Sprintf("%s_%d", table.Name, part+1)
+		opt.Table = &table
+	}
+	opt.Charts, opt.PivotTables = nil, nil
+	return opt
+}
+
+// Zip splits a dataset into workbooks of at most MaxRows rows each and
+// bundles them in a zip archive, for exports too large to open as a single
+// workbook.
+type Zip struct {
+	name  string
+	parts []*Xlsx
+}
+
+// NewZip splits tData into workbooks named after FileName, e.g.
+// products-1.xlsx and products-2.xlsx.
+func NewZip[T any](tData []T, opt XlsxOptions) *Zip {
+	name := strings.TrimSuffix(filepath.Base(opt.FileName), filepath.Ext(opt.FileName))
+	if name == "" || name == "." {
+		name = "export"
+	}
+
+	z := &Zip{name: name}
+	limit := maxSheetRows(opt)
+	for start := 0; start == 0 || start < len(tData); start += limit {
+		z.parts = append(z.parts, NewXlsx(tData[start:min(start+limit, len(tData))], opt))
+	}
+	return z
+}
+
+func (z *Zip) ContentType() string {
+	return "application/zip"
+}
+
+func (z *Zip) Extension() string {
+	return "zip"
+}
+
+func (z *Zip) Write(w io.Writer) error {
+	archive := zip.NewWriter(w)
+	for i, part := range z.parts {
+		name := fmt.Sprintf("%s-%d.%s", z.name, i+1, part.Extension())
+		entry, err := archive.Create(name)
+		if err != nil {
+			return fmt.Errorf("failed to add %s: %v", name, err)
+		}
+		if err := part.Write(entry); err != nil {
+			return fmt.Errorf("failed to write %s: %v", name, err)
+		}
+	}
+	return archive.Close()
+}
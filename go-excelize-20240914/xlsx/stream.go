@@ -0,0 +1,475 @@
+package xlsx
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// StreamWriter writes rows through excelize's StreamWriter as they are
+// produced, so memory use stays flat for very large exports. Use NewXlsx
+// for small datasets that fit comfortably in memory.
+type StreamWriter[T any] struct {
+	file      *excelize.File
+	stream    *excelize.StreamWriter
+	opt       XlsxOptions
+	columns   []column
+	styles    []int
+	linkStyle int
+	started   bool
+	pending   [][]any
+	sheetName string
+	fileName  string
+	row       int
+	// baseOpt and baseSheet are the options and name of the first part of
+	// a sheet that rolls over after maxRows data rows
+	baseOpt   XlsxOptions
+	baseSheet string
+	part      int
+	maxRows   int
+	// sized is set when the columns depend on the values of the rows
+	sized bool
+	// extra holds the in-memory sheets added with AddStreamSheet
+	extra *Xlsx
+}
+
+func NewStreamWriter[T any](opt XlsxOptions) (*StreamWriter[T], error) {
+	f := excelize.NewFile()
+
+	sheetName := opt.Sheet
+	if sheetName == "" {
+		sheetName = "Sheet1"
+	} else if err := f.SetSheetName("Sheet1", sheetName); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("failed to rename sheet: %v", err)
+	}
+
+	if font := localeOf(opt).font; font != "" {
+		if err := f.SetDefaultFont(font); err != nil {
+			f.Close()
+			return nil, fmt.Errorf("failed to set font %s: %v", font, err)
+		}
+	}
+
+	stream, err := f.NewStreamWriter(sheetName)
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("failed to create stream writer: %v", err)
+	}
+
+	s := &StreamWriter[T]{
+		file:      f,
+		stream:    stream,
+		opt:       opt,
+		sheetName: sheetName,
+		fileName:  opt.FileName,
+		baseOpt:   opt,
+		baseSheet: sheetName,
+		maxRows:   maxSheetRows(opt),
+	}
+
+	// Struct columns are known from the type, map columns and nested slices
+	// and maps only once the first row arrives
+	columns := flatten(schemaOf(reflect.TypeFor[T](), nil), nil, flattenDepth(opt))
+	s.sized = columns == nil || sizedByData(columns)
+	if (columns != nil && !sizedByData(columns)) || (columns == nil && len(opt.Headers) > 0) {
+		s.prepare(reflect.Value{})
+		if !opt.AutoFit {
+			if err := s.start(); err != nil {
+				f.Close()
+				return nil, err
+			}
+		}
+	}
+	return s, nil
+}
+
+// prepare resolves the columns of the sheet from the type and the sample
+// row, which is the invalid Value until the first row arrives.
+func (s *StreamWriter[T]) prepare(sample reflect.Value) {
+	var rows []reflect.Value
+	var values [][]any
+	if sample.IsValid() {
+		rows = []reflect.Value{sample}
+	}
+	columns := schemaOf(reflect.TypeFor[T](), rows)
+	columns = selectColumns(flatten(columns, rows, flattenDepth(s.opt)), s.opt.Headers)
+	if sample.IsValid() {
+		values = [][]any{normalizeRow(rowValues(columns, sample), location(s.opt))}
+	}
+	s.columns = withFormulas(prepareColumns(columns, values, s.opt), s.opt)
+}
+
+// sizedByData reports whether some columns hold slices, maps or interfaces,
+// which are only flattened once their values are known.
+func sizedByData(columns []column) bool {
+	for _, c := range columns {
+		t := c.typ
+		for t != nil && t.Kind() == reflect.Pointer {
+			t = t.Elem()
+		}
+		if t == nil || (isNestedKind(t.Kind()) && !isScalarType(t)) {
+			return true
+		}
+	}
+	return false
+}
+
+// start fixes the column widths and writes the styled header row, followed
+// by the rows buffered to measure them. excelize requires the widths to be
+// set before any row is streamed.
+func (s *StreamWriter[T]) start() error {
+	s.started = true
+	// Later parts keep the widths measured for the first
+	if s.opt.AutoFit && s.part == 0 {
+		autoFit(s.columns, s.pending, s.opt)
+	}
+	s.styles = make([]int, len(s.columns))
+
+	p, err := panes(s.opt)
+	if err != nil {
+		return err
+	}
+	if p != nil {
+		if err := s.stream.SetPanes(p); err != nil {
+			return fmt.Errorf("failed to freeze panes: %v", err)
+		}
+	}
+
+	for i, c := range s.columns {
+		if err := s.stream.SetColWidth(i+1, i+1, c.colWidth()); err != nil {
+			return fmt.Errorf("failed to set column width: %v", err)
+		}
+		style, err := newDataStyle(s.file, c, s.opt.Protection.editable(c))
+		if err != nil {
+			return fmt.Errorf("failed to create number format style: %v", err)
+		}
+		s.styles[i] = style
+		if (c.link || c.image) && s.linkStyle == 0 {
+			style, err := s.file.NewStyle(linkStyle)
+			if err != nil {
+				return fmt.Errorf("failed to create link style: %v", err)
+			}
+			s.linkStyle = style
+		}
+	}
+	if len(s.columns) == 0 {
+		return nil
+	}
+
+	style, err := newHeaderStyle(s.file, s.opt.HeaderStyle)
+	if err != nil {
+		return fmt.Errorf("failed to create header style: %v", err)
+	}
+
+	cells := make([]any, len(s.columns))
+	for i, c := range s.columns {
+		cells[i] = excelize.Cell{StyleID: style, Value: c.header}
+	}
+	if err := s.setRow(cells); err != nil {
+		return err
+	}
+
+	pending := s.pending
+	s.pending = nil
+	for _, values := range pending {
+		if err := s.writeValues(values); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *StreamWriter[T]) setRow(values []any) error {
+	s.row++
+	cell, err := excelize.CoordinatesToCellName(1, s.row)
+	if err != nil {
+		return err
+	}
+	return s.stream.SetRow(cell, values)
+}
+
+// WriteRow streams a single item as the next row of the sheet. Map keys and
+// the elements of nested slices and maps take their columns from the first
+// item, and a later item that needs more columns, such as a longer slice or
+// a new key, is rejected rather than losing those values. Headers in
+// XlsxOptions narrow the columns that are checked.
+// With AutoFit the first rows are held back until the widths are measured.
+func (s *StreamWriter[T]) WriteRow(v T) error {
+	rv := reflect.ValueOf(&v).Elem()
+
+	if s.columns == nil {
+		s.prepare(rv)
+	}
+	if s.sized {
+		if name, ok := unknownColumn(reflect.TypeFor[T](), s.columns, rv, s.opt); ok {
+			return fmt.Errorf("row value %s is not a column of the first row", name)
+		}
+	}
+
+	values := normalizeRow(rowValues(s.columns, rv), location(s.opt))
+	if !s.started {
+		if s.opt.AutoFit && len(s.pending) < autoFitSampleRows {
+			s.pending = append(s.pending, values)
+			return nil
+		}
+		if err := s.start(); err != nil {
+			return err
+		}
+	}
+	return s.writeValues(values)
+}
+
+// unknownColumn returns the name of a column that row v has on its own but
+// the prepared columns do not hold
+func unknownColumn(t reflect.Type, columns []column, v reflect.Value, opt XlsxOptions) (string, bool) {
+	rows := []reflect.Value{v}
+	for _, c := range selectColumns(flatten(schemaOf(t, rows), rows, flattenDepth(opt)), opt.Headers) {
+		if !holds(columns, c.name) {
+			return c.name, true
+		}
+	}
+	return "", false
+}
+
+// holds reports whether columns has a column for the value named name, as
+// is, flattened further or as part of a value it holds whole
+func holds(columns []column, name string) bool {
+	for _, c := range columns {
+		if c.formula != "" {
+			continue
+		}
+		if c.name == name || nestedName(c.name, name) || nestedName(name, c.name) {
+			return true
+		}
+	}
+	return false
+}
+
+// nestedName reports whether name was flattened from the value named parent
+func nestedName(name, parent string) bool {
+	return strings.HasPrefix(name, parent+".") || strings.HasPrefix(name, parent+"[")
+}
+
+func (s *StreamWriter[T]) writeValues(values []any) error {
+	if s.row-1 >= s.maxRows {
+		if err := s.rollover(); err != nil {
+			return err
+		}
+	}
+
+	for i, c := range s.columns {
+		if c.formula != "" {
+			formula, err := rowFormula(s.columns, c.formula, s.row+1)
+			if err != nil {
+				return fmt.Errorf("invalid formula of %s: %v", c.header, err)
+			}
+			values[i] = excelize.Cell{StyleID: s.styles[i], Formula: formula}
+			continue
+		}
+
+		// Images cannot be embedded while streaming, so they become links
+		if url, ok := values[i].(string); ok && url != "" && (c.link || c.image) {
+			values[i] = hyperlinkCell(url, s.linkStyle)
+		} else if s.styles[i] != 0 {
+			values[i] = excelize.Cell{StyleID: s.styles[i], Value: values[i]}
+		}
+	}
+	return s.setRow(values)
+}
+
+func (s *StreamWriter[T]) WriteRows(data []T) error {
+	for _, v := range data {
+		if err := s.WriteRow(v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// rollover ends the current sheet and continues on a new one with the same
+// header and styles
+func (s *StreamWriter[T]) rollover() error {
+	if err := s.finishSheet(); err != nil {
+		return err
+	}
+
+	s.part++
+	name := partName(s.baseSheet, s.part)
+	if _, err := s.file.NewSheet(name); err != nil {
+		return fmt.Errorf("failed to create sheet %s: %v", name, err)
+	}
+	stream, err := s.file.NewStreamWriter(name)
+	if err != nil {
+		return fmt.Errorf("failed to create stream writer: %v", err)
+	}
+	s.stream, s.sheetName, s.row = stream, name, 0
+	s.opt = partOptions(s.baseOpt, s.part)
+	return s.start()
+}
+
+// AddStreamSheet adds a sheet built from tData after the streamed one, like
+// AddSheet does for an in-memory workbook. The sheet is held in memory, so
+// it suits small datasets such as the rows the stream flagged. It returns s
+// so calls can be chained.
+func AddStreamSheet[T, U any](s *StreamWriter[T], tData []U, opt XlsxOptions) *StreamWriter[T] {
+	if s.extra == nil {
+		s.extra = &Xlsx{file: s.file}
+	}
+	AddSheet(s.extra, tData, opt)
+	return s
+}
+
+// flush ends the last sheet, writes the sheets added with AddStreamSheet and
+// adds the provenance of the workbook
+func (s *StreamWriter[T]) flush() error {
+	if err := s.finishSheet(); err != nil {
+		return err
+	}
+	if err := s.writeExtra(); err != nil {
+		return err
+	}
+	return writeAbout(s.file, s.opt.About)
+}
+
+func (s *StreamWriter[T]) writeExtra() error {
+	if s.extra == nil {
+		return nil
+	}
+	for _, sh := range s.extra.sheets {
+		if idx, _ := s.file.GetSheetIndex(sh.name); idx != -1 {
+			return fmt.Errorf("duplicate sheet name %s", sh.name)
+		}
+		if _, err := s.file.NewSheet(sh.name); err != nil {
+			return fmt.Errorf("failed to create sheet %s: %v", sh.name, err)
+		}
+		if err := s.extra.renderSheet(sh); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// finishSheet applies the sheet rules, which excelize writes after the rows,
+// and ends the stream of the current sheet
+func (s *StreamWriter[T]) finishSheet() error {
+	if !s.started {
+		if s.columns == nil {
+			s.prepare(reflect.Value{})
+		}
+		if err := s.start(); err != nil {
+			return err
+		}
+	}
+	if err := applyRules(s.file, s.sheetName, s.columns, max(s.row-1, 0), s.opt); err != nil {
+		return err
+	}
+	if err := s.addTable(); err != nil {
+		return err
+	}
+	if err := s.writeSummaries(); err != nil {
+		return err
+	}
+	if err := protect(s.file, s.sheetName, s.opt.Protection); err != nil {
+		return err
+	}
+	if err := s.stream.Flush(); err != nil {
+		return fmt.Errorf("failed to flush stream: %v", err)
+	}
+	return nil
+}
+
+// addTable adds the table or autofilter over the streamed rows
+func (s *StreamWriter[T]) addTable() error {
+	if len(s.columns) == 0 || (s.opt.Table == nil && !s.opt.AutoFilter) {
+		return nil
+	}
+	rows := max(s.row-1, 0)
+	ref, err := dataRange(len(s.columns), rows)
+	if err != nil {
+		return err
+	}
+
+	if s.opt.Table != nil {
+		if rows == 0 {
+			// The blank row below the header must exist in the stream
+			if err := s.setRow(nil); err != nil {
+				return err
+			}
+		}
+		if err := s.stream.AddTable(newTable(s.opt.Table, ref)); err != nil {
+			return fmt.Errorf("failed to add table: %v", err)
+		}
+		return nil
+	}
+	if err := s.file.AutoFilter(s.sheetName, ref, nil); err != nil {
+		return fmt.Errorf("failed to add autofilter: %v", err)
+	}
+	return nil
+}
+
+// writeSummaries streams the summary rows below the data, which must come
+// after every data row
+func (s *StreamWriter[T]) writeSummaries() error {
+	if len(s.opt.Summaries) == 0 || len(s.columns) == 0 {
+		return nil
+	}
+	styles, err := newSummaryStyles(s.file, s.columns)
+	if err != nil {
+		return err
+	}
+
+	// Like the table, the range covers at least one row below the header
+	last := max(s.row, 2)
+	for s.row < last {
+		if err := s.setRow(nil); err != nil {
+			return err
+		}
+	}
+	for _, sum := range s.opt.Summaries {
+		cells, err := summaryCells(s.columns, sum, last, styles)
+		if err != nil {
+			return err
+		}
+		values := make([]any, len(cells))
+		for j, c := range cells {
+			values[j] = c
+		}
+		if err := s.setRow(values); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SaveExcelFile flushes the stream and saves the workbook to FileName.
+func (s *StreamWriter[T]) SaveExcelFile() error {
+	defer s.file.Close()
+
+	if err := s.flush(); err != nil {
+		return err
+	}
+	return saveFile(s.fileName, func(w io.Writer) error {
+		return s.file.Write(w, excelize.Options{Password: s.opt.Password})
+	})
+}
+
+// Close discards a workbook that will not be written, removing the
+// temporary files of the stream. Write and SaveExcelFile close it already.
+func (s *StreamWriter[T]) Close() error {
+	return s.file.Close()
+}
+
+// Write flushes the stream and writes the workbook to w instead of saving
+// it to FileName.
+func (s *StreamWriter[T]) Write(w io.Writer) error {
+	defer s.file.Close()
+
+	if err := s.flush(); err != nil {
+		return err
+	}
+	return s.file.Write(w, excelize.Options{Password: s.opt.Password})
+}
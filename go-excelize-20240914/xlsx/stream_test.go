@@ -0,0 +1,93 @@
+package xlsx
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type taggedRow struct {
+	Name string
+	Tags []string
+}
+
+func TestStreamWriterRejectsLongerSlice(t *testing.T) {
+	sw, err := NewStreamWriter[taggedRow](XlsxOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sw.Close()
+
+	if err := sw.WriteRow(taggedRow{Name: "a", Tags: []string{"x"}}); err != nil {
+		t.Fatalf("first row: %v", err)
+	}
+	if err := sw.WriteRow(taggedRow{Name: "b"}); err != nil {
+		t.Fatalf("shorter row: %v", err)
+	}
+	err = sw.WriteRow(taggedRow{Name: "c", Tags: []string{"x", "y", "z"}})
+	if err == nil || !strings.Contains(err.Error(), "Tags[1]") {
+		t.Fatalf("longer row: got %v, want an error naming Tags[1]", err)
+	}
+}
+
+func TestStreamWriterRejectsNewMapKey(t *testing.T) {
+	sw, err := NewStreamWriter[map[string]any](XlsxOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sw.Close()
+
+	if err := sw.WriteRow(map[string]any{"a": 1}); err != nil {
+		t.Fatal(err)
+	}
+	if err := sw.WriteRow(map[string]any{"a": 1, "b": 2}); err == nil {
+		t.Fatal("want an error for the new key b")
+	}
+}
+
+func TestStreamWriterHeadersNarrowKeys(t *testing.T) {
+	sw, err := NewStreamWriter[map[string]any](XlsxOptions{Headers: []string{"a", "b"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows := []map[string]any{{"a": 1}, {"a": 2, "b": 3, "c": 4}}
+	if err := sw.WriteRows(rows); err != nil {
+		t.Fatalf("WriteRows: %v", err)
+	}
+	got := writeRows(t, sw, "")
+	want := [][]string{{"a", "b"}, {"1"}, {"2", "3"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("rows = %v, want %v", got, want)
+	}
+}
+
+func TestStreamWriterRejectsLongerSliceWithHeaders(t *testing.T) {
+	sw, err := NewStreamWriter[taggedRow](XlsxOptions{Headers: []string{"Name", "Tags"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sw.Close()
+
+	if err := sw.WriteRow(taggedRow{Name: "a", Tags: []string{"x"}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := sw.WriteRow(taggedRow{Name: "b", Tags: []string{"x", "y"}}); err == nil {
+		t.Fatal("want an error for Tags[1]")
+	}
+}
+
+func TestStreamWriterMatchesNewXlsx(t *testing.T) {
+	rows := []taggedRow{{Name: "a", Tags: []string{"x", "y"}}, {Name: "b", Tags: []string{"z"}}}
+	sw, err := NewStreamWriter[taggedRow](XlsxOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := sw.WriteRows(rows); err != nil {
+		t.Fatalf("WriteRows: %v", err)
+	}
+	got := writeRows(t, sw, "")
+	want := writeRows(t, NewXlsx(rows, XlsxOptions{}), "")
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("streamed %v, want %v", got, want)
+	}
+}
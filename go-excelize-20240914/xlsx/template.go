@@ -0,0 +1,158 @@
+package xlsx
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"text/template"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// rowMarker matches the cells of the repeating row of a template, which
+// name the column whose values fill them, e.g. [[Unit Price]]
+var rowMarker = regexp.MustCompile(`^\[\[(.+)\]\]$`)
+
+// renderTemplate fills the sheets of a template workbook. Every text cell
+// with {{...}} actions is executed as a text/template against TemplateData,
+// then each data sheet fills the template sheet of the same name, or the
+// first template sheet for the first data sheet. Data sheets without a
+// template sheet are generated as usual.
+func (f *Xlsx) renderTemplate() error {
+	for _, name := range f.file.GetSheetList() {
+		if err := f.fillPlaceholders(name); err != nil {
+			return err
+		}
+	}
+
+	for i, s := range f.sheets {
+		if idx, _ := f.file.GetSheetIndex(s.name); idx == -1 {
+			if i > 0 {
+				if _, err := f.file.NewSheet(s.name); err != nil {
+					return fmt.Errorf("failed to create sheet %s: %v", s.name, err)
+				}
+				if err := f.renderSheet(s); err != nil {
+					return err
+				}
+				continue
+			}
+			if err := f.file.SetSheetName(f.file.GetSheetName(0), s.name); err != nil {
+				return fmt.Errorf("failed to rename sheet %s: %v", s.name, err)
+			}
+		}
+
+		s.templated = true
+		if err := f.fillRows(s); err != nil {
+			return err
+		}
+		// Editable cells are unlocked in the template itself
+		if err := protect(f.file, s.name, s.opt.Protection); err != nil {
+			return err
+		}
+	}
+	return f.addDashboards()
+}
+
+// fillPlaceholders executes the {{...}} actions in the text cells of a
+// template sheet
+func (f *Xlsx) fillPlaceholders(name string) error {
+	rows, err := f.file.GetRows(name, excelize.Options{RawCellValue: true})
+	if err != nil {
+		return fmt.Errorf("failed to read template sheet %s: %v", name, err)
+	}
+
+	for i, row := range rows {
+		for j, text := range row {
+			if !strings.Contains(text, "{{") {
+				continue
+			}
+			cell, err := excelize.CoordinatesToCellName(j+1, i+1)
+			if err != nil {
+				return err
+			}
+
+			tmpl, err := template.New(cell).Option("missingkey=error").Parse(text)
+			if err != nil {
+				return fmt.Errorf("invalid placeholder in %s!%s: %v", name, cell, err)
+			}
+			var b strings.Builder
+			if err := tmpl.Execute(&b, f.data); err != nil {
+				return fmt.Errorf("failed to fill %s!%s: %v", name, cell, err)
+			}
+			if err := f.file.SetCellValue(name, cell, b.String()); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// fillRows repeats the marked row of a template sheet once per data row,
+// keeping the styles of its cells. Rows below it move down, so footers
+// stay under the data.
+func (f *Xlsx) fillRows(s *sheet) error {
+	rows, err := f.file.GetRows(s.name, excelize.Options{RawCellValue: true})
+	if err != nil {
+		return fmt.Errorf("failed to read template sheet %s: %v", s.name, err)
+	}
+
+	marked, markers := 0, []int(nil)
+	for i, row := range rows {
+		for j, text := range row {
+			m := rowMarker.FindStringSubmatch(strings.TrimSpace(text))
+			if m == nil {
+				continue
+			}
+			if markers == nil {
+				markers = make([]int, len(row))
+				for k := range markers {
+					markers[k] = -1
+				}
+			}
+			if markers[j] = columnIndex(s.columns, m[1]); markers[j] < 0 {
+				return fmt.Errorf("unknown column %q in template sheet %s", m[1], s.name)
+			}
+		}
+		if markers != nil {
+			marked = i + 1
+			break
+		}
+	}
+	if markers == nil {
+		return fmt.Errorf("template sheet %s has no [[Column]] row", s.name)
+	}
+
+	if len(s.rows) == 0 {
+		return f.file.RemoveRow(s.name, marked)
+	}
+	if len(s.rows) > 1 {
+		if err := f.file.InsertRows(s.name, marked+1, len(s.rows)-1); err != nil {
+			return fmt.Errorf("failed to insert rows: %v", err)
+		}
+		for k := range markers {
+			top, _ := excelize.CoordinatesToCellName(k+1, marked)
+			style, err := f.file.GetCellStyle(s.name, top)
+			if err != nil || style == 0 {
+				continue
+			}
+			bottom, _ := excelize.CoordinatesToCellName(k+1, marked+len(s.rows)-1)
+			f.file.SetCellStyle(s.name, top, bottom, style)
+		}
+	}
+
+	for i, row := range s.rows {
+		for k, j := range markers {
+			if j < 0 {
+				continue
+			}
+			cell, err := excelize.CoordinatesToCellName(k+1, marked+i)
+			if err != nil {
+				return err
+			}
+			if err := f.file.SetCellValue(s.name, cell, row[j]); err != nil {
+				return fmt.Errorf("failed to set %s!%s: %v", s.name, cell, err)
+			}
+		}
+	}
+	return nil
+}
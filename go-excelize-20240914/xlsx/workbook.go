@@ -0,0 +1,81 @@
+package xlsx
+
+import (
+	"fmt"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// sheet is one typed sheet of a workbook with its own headers, styles and
+// options.
+type sheet struct {
+	name    string
+	columns []column
+	rows    [][]any
+	opt     XlsxOptions
+	// templated sheets are filled from a template instead of generated
+	templated bool
+	// changes holds the previous values of the changed cells of a diff
+	// report, keyed by row and column index
+	changes map[[2]int]any
+}
+
+// NewWorkbook creates an empty workbook saved to opt.FileName, or opens
+// opt.Template to fill it. Sheets are added with AddSheet, for example:
+//
+//	wb := xlsx.NewWorkbook(xlsx.XlsxOptions{FileName: "products.xlsx"})
+//	xlsx.AddSheet(wb, products, xlsx.XlsxOptions{Sheet: "Products"})
+//	xlsx.AddSheet(wb, lowStock, xlsx.XlsxOptions{Sheet: "Low Stock"})
+//	err := wb.SaveExcelFile()
+func NewWorkbook(opt XlsxOptions) *Xlsx {
+	x := &Xlsx{
+		file:     excelize.NewFile(),
+		fileName: opt.FileName,
+		password: opt.Password,
+		font:     localeOf(opt).font,
+		about:    opt.About,
+	}
+	if opt.Template != "" {
+		// A template that cannot be opened fails when the workbook is saved
+		f, err := excelize.OpenFile(opt.Template)
+		if err != nil {
+			x.err = fmt.Errorf("failed to open template %s: %v", opt.Template, err)
+			return x
+		}
+		x.file.Close()
+		x.file, x.template, x.data = f, true, opt.TemplateData
+	}
+	return x
+}
+
+// AddSheet appends a sheet built from tData using the sheet level settings
+// of opt, such as headers, sheet name and styles. It returns x so calls can be chained.
+func AddSheet[T any](x *Xlsx, tData []T, opt XlsxOptions) *Xlsx {
+	name := opt.Sheet
+	if name == "" {
+		name = fmt.Sprintf("Sheet%d", len(x.sheets)+1)
+	}
+
+	columns, rows := tabulate(tData, opt)
+	if len(opt.Formulas) > 0 {
+		columns = withFormulas(columns, opt)
+		for i := range rows {
+			rows[i] = append(rows[i], make([]any, len(opt.Formulas))...)
+		}
+	}
+	if opt.AutoFit {
+		autoFit(columns, rows, opt)
+	}
+
+	// Rows past the limit of a sheet go on to the next part
+	limit := maxSheetRows(opt)
+	for part := 0; part == 0 || part*limit < len(rows); part++ {
+		x.sheets = append(x.sheets, &sheet{
+			name:    partName(name, part),
+			columns: columns,
+			rows:    rows[part*limit : min((part+1)*limit, len(rows))],
+			opt:     partOptions(opt, part),
+		})
+	}
+	return x
+}
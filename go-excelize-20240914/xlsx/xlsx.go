@@ -1,154 +1,310 @@
 package xlsx
 
 import (
-	"encoding/json"
 	"fmt"
-	"os"
+	"io"
+	"time"
 
 	"github.com/xuri/excelize/v2"
 )
 
+// ContentType is the MIME type of .xlsx workbooks.
+const ContentType = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+
 type XlsxOptions struct {
 	FileName string
 	Headers  []string
 	Sheet    string
+	// HeaderStyle replaces the default blue header style of the sheet.
+	HeaderStyle *excelize.Style
+	// Location is the time zone time.Time values are written in,
+	// time.Local by default.
+	Location *time.Location
+	// DateFormat, IntFormat and FloatFormat are the Excel number formats
+	// of columns that have no format of their own, e.g. "#,##0.00".
+	DateFormat  string
+	IntFormat   string
+	FloatFormat string
+	// Columns overrides the tag settings of single columns, keyed by
+	// header, json name or field name.
+	Columns map[string]ColumnOptions
+	// ImageFetcher downloads the images of `image` columns so they can be
+	// embedded as thumbnails. Without it image columns become hyperlinks.
+	ImageFetcher func(url string) ([]byte, error)
+	// ImageHeight is the row height in points of rows with a thumbnail.
+	ImageHeight float64
+	// ConditionalFormats and Validations turn into Excel conditional
+	// formatting and data-validation rules on their columns.
+	ConditionalFormats []ConditionalFormat
+	Validations        []Validation
+	// AutoFit sizes columns without an explicit width to their content,
+	// between MinWidth and MaxWidth (8 and 60 by default).
+	AutoFit  bool
+	MinWidth float64
+	MaxWidth float64
+	// Table turns the data range into an Excel table. AutoFilter only adds
+	// filter buttons to the header and is implied by Table.
+	Table      *TableOptions
+	AutoFilter bool
+	// FreezeHeader keeps the header row and FreezeColumns the first N
+	// columns in view while scrolling.
+	FreezeHeader  bool
+	FreezeColumns int
+	// Formulas add computed columns and Summaries add aggregate rows below
+	// the data, both written as Excel formulas. They only apply to xlsx.
+	Formulas  []Formula
+	Summaries []Summary
+	// Charts and PivotTables add sheets that summarize this sheet's data.
+	// They are only supported by NewXlsx and AddSheet, not when streaming.
+	Charts      []Chart
+	PivotTables []PivotTable
+	// Template is the path of a designer-made workbook that NewWorkbook
+	// fills instead of starting from a blank one. Its {{...}} placeholders
+	// are filled from TemplateData and the row of [[Column]] cells is
+	// repeated for every data row; styles, widths and other content come
+	// from the template, so sheet options that lay out the data only apply
+	// to sheets the template does not have.
+	Template     string
+	TemplateData any
+	// Password encrypts the workbook so it can only be opened with it. It is
+	// taken from the options of NewWorkbook.
+	Password string
+	// Protection locks the sheet against edits except in editable columns.
+	Protection *Protection
+	// FlattenDepth is the number of levels of nested structs, maps and
+	// slices written as columns of their own, such as `data.name` and
+	// `tags[0]`; deeper values are written as JSON. It defaults to 3 and a
+	// negative depth writes every nested value as JSON.
+	FlattenDepth int
+	// Locale, such as "th" or "en-US", translates the headers from Catalog
+	// and picks the number and date formats of the language and a font that
+	// renders its script. BuddhistEra shows the years of dates in the Thai
+	// Buddhist era.
+	Locale      string
+	Catalog     Catalog
+	BuddhistEra bool
+	// Currency is the ISO 4217 code of the currency columns without one in
+	// their tag, e.g. THB. It does not follow the locale, as the language of
+	// the reader does not change what the amounts are in.
+	Currency string
+	// About fills the document properties and an optional provenance
+	// sheet. It is taken from the options of NewWorkbook.
+	About *About
+	// MaxRows is the number of data rows per sheet before the rows roll
+	// over to "Sheet (2)", "Sheet (3)" and so on, each with the header and
+	// styles of the first. It defaults to the most that fit in Excel's
+	// 1,048,576 rows.
+	MaxRows int
 }
 
 type Xlsx struct {
-	file      *excelize.File
-	data      []map[string]any
-	headers   []string
-	sheetName string
-	fileName  string
+	file     *excelize.File
+	sheets   []*sheet
+	fileName string
+	// template is set for workbooks opened from XlsxOptions.Template
+	template bool
+	data     any
+	password string
+	font     string
+	about    *About
+	err      error
 }
 
+// NewXlsx creates a workbook with a single sheet holding tData. Use
+// NewWorkbook and AddSheet to build a workbook with several sheets.
 func NewXlsx[T any](tData []T, opt XlsxOptions) *Xlsx {
-	f := excelize.NewFile()
-	headers := opt.Headers
+	return AddSheet(NewWorkbook(opt), tData, opt)
+}
 
-	sheetName := opt.Sheet
-	if sheetName == "" {
-		sheetName = "Sheet1"
-	}
+// RemoveExistingFile removes FileName, waiting for any save of it in
+// progress to finish first.
+func (f *Xlsx) RemoveExistingFile() error {
+	return removeFile(f.fileName)
+}
 
-	data, err := convertToMapSlice(tData)
-	if err != nil {
-		fmt.Println("Error converting to map slice:", err)
+// SaveExcelFile renders the workbook and saves it to FileName. The file is
+// replaced atomically, and concurrent saves of the same file take turns.
+func (f *Xlsx) SaveExcelFile() error {
+	defer f.file.Close()
+
+	if err := f.render(); err != nil {
+		return err
 	}
 
-	if len(headers) == 0 {
-		for k := range data[0] {
-			headers = append(headers, k)
-		}
+	// Save the new Excel file
+	return saveFile(f.fileName, func(w io.Writer) error {
+		return f.file.Write(w, excelize.Options{Password: f.password})
+	})
+}
+
+// Write renders the workbook to w instead of saving it to FileName.
+func (f *Xlsx) Write(w io.Writer) error {
+	defer f.file.Close()
+
+	if err := f.render(); err != nil {
+		return err
 	}
+	return f.file.Write(w, excelize.Options{Password: f.password})
+}
 
-	return &Xlsx{
-		f,
-		data,
-		headers,
-		sheetName,
-		opt.FileName,
+// render creates every sheet and writes its headers and data, followed by
+// the provenance of the workbook
+func (f *Xlsx) render() error {
+	if f.err != nil {
+		return f.err
+	}
+	render := f.renderSheets
+	if f.template {
+		render = f.renderTemplate
 	}
+	if err := render(); err != nil {
+		return err
+	}
+	return writeAbout(f.file, f.about)
 }
 
-func (f *Xlsx) RemoveExistingFile() error {
-	if _, err := os.Stat(f.fileName); err == nil {
-		if err := os.Remove(f.fileName); err != nil {
-			return fmt.Errorf("failed to remove existing file: %v", err)
+func (f *Xlsx) renderSheets() error {
+	// The default font must be set before any style is created
+	if f.font != "" {
+		if err := f.file.SetDefaultFont(f.font); err != nil {
+			return fmt.Errorf("failed to set font %s: %v", f.font, err)
 		}
 	}
-	return nil
-}
-func toAlphaString(colIndex int) string {
-	colLetter := ""
-	for colIndex >= 0 {
-		colLetter = string('A'+(colIndex%26)) + colLetter
-		colIndex = colIndex/26 - 1
+
+	for i, s := range f.sheets {
+		if i == 0 {
+			// Reuse the default sheet of a new file for the first one
+			if err := f.file.SetSheetName("Sheet1", s.name); err != nil {
+				return fmt.Errorf("failed to rename sheet %s: %v", s.name, err)
+			}
+		} else {
+			if idx, _ := f.file.GetSheetIndex(s.name); idx != -1 {
+				return fmt.Errorf("duplicate sheet name %s", s.name)
+			}
+			if _, err := f.file.NewSheet(s.name); err != nil {
+				return fmt.Errorf("failed to create sheet %s: %v", s.name, err)
+			}
+		}
+
+		if err := f.renderSheet(s); err != nil {
+			return err
+		}
 	}
-	return colLetter
+	return f.addDashboards()
 }
 
-func (f *Xlsx) SaveExcelFile() error {
-	defer f.file.Close()
+func (f *Xlsx) renderSheet(s *sheet) error {
+	// Column styles come first as they restyle the cells already written
+	if err := f.unlockColumns(s); err != nil {
+		return err
+	}
 
-	// for i, header := range f.headers {
-	// 	cell := fmt.Sprintf("%s1", string(rune('A'+i)))
-	// 	f.file.SetCellValue(f.sheetName, cell, header)
-	// }
-	for i, header := range f.headers {
-		cell := fmt.Sprintf("%s1", string(rune('A'+i)))
-
-		// f.SetCellValue(sheetName, cell, header)
-
-		// Set font to bold, white color
-
-		style, _ := f.file.NewStyle(&excelize.Style{
-			Font: &excelize.Font{
-				Bold:  true,
-				Size:  10,
-				Color: "FFFFFFFF",
-			},
-			Fill: excelize.Fill{
-				Type:    "pattern",
-				Pattern: 1,
-				Color:   []string{"3c98f2"},
-			},
-			Alignment: &excelize.Alignment{
-				Horizontal: "center",
-				Vertical:   "center",
-			},
-			Border: []excelize.Border{
-				{Type: "top", Color: "FF000000", Style: 1},
-				{Type: "left", Color: "FF000000", Style: 1},
-				{Type: "bottom", Color: "FF000000", Style: 1},
-				{Type: "right", Color: "FF000000", Style: 1},
-			},
-		})
-		f.file.SetCellStyle(f.sheetName, cell, cell, style)
-		f.file.SetCellValue(f.sheetName, cell, header)
+	style, err := newHeaderStyle(f.file, s.opt.HeaderStyle)
+	if err != nil {
+		return fmt.Errorf("failed to create header style: %v", err)
+	}
+
+	for i, c := range s.columns {
+		cell, err := excelize.CoordinatesToCellName(i+1, 1)
+		if err != nil {
+			return fmt.Errorf("too many columns: %v", err)
+		}
+		f.file.SetCellStyle(s.name, cell, cell, style)
+		f.file.SetCellValue(s.name, cell, c.header)
 	}
 
 	// Adjust column widths
-	for colIndex := 0; colIndex < len(f.headers); colIndex++ {
-		column := toAlphaString(colIndex)
-		f.file.SetColWidth(f.sheetName, column, column, 20)
+	for colIndex, c := range s.columns {
+		column, _ := excelize.ColumnNumberToName(colIndex + 1)
+		f.file.SetColWidth(s.name, column, column, c.colWidth())
 	}
 
 	// Populate the sheet with data
-	f.writeData(f.data, f.headers)
-
-	// Save the new Excel file
-	return f.file.SaveAs(f.fileName)
-}
-
-// writeData writes the data to the Excel sheet starting from row 2
-func (f *Xlsx) writeData(data []map[string]interface{}, headers []string) {
-	for i, row := range data {
-		for j, header := range headers {
-			cell := fmt.Sprintf("%s%d", string(rune('A'+j)), i+2)
-			f.file.SetCellValue("Sheet1", cell, row[header])
-		}
+	if err := f.writeData(s); err != nil {
+		return err
 	}
+	if err := f.writeSummaries(s); err != nil {
+		return err
+	}
+	if err := f.writeChanges(s); err != nil {
+		return err
+	}
+	if err := f.writeMedia(s); err != nil {
+		return err
+	}
+	if err := applyRules(f.file, s.name, s.columns, len(s.rows), s.opt); err != nil {
+		return err
+	}
+	if err := f.applyLayout(s); err != nil {
+		return err
+	}
+	return protect(f.file, s.name, s.opt.Protection)
 }
 
-func convertToMapSlice[T any](tData []T) ([]map[string]interface{}, error) {
-	data := make([]map[string]interface{}, 0, len(tData))
+// writeData writes the data to the Excel sheet starting from row 2
+func (f *Xlsx) writeData(s *sheet) error {
+	for i, row := range s.rows {
+		for j, c := range s.columns {
+			cell, err := excelize.CoordinatesToCellName(j+1, i+2)
+			if err != nil {
+				return err
+			}
+			if c.formula == "" {
+				f.file.SetCellValue(s.name, cell, row[j])
+				continue
+			}
 
-	for _, v := range tData {
-		// Marshal the item into JSON
-		b, err := json.Marshal(v)
-		if err != nil {
-			return nil, fmt.Errorf("error marshaling item: %v", err)
+			formula, err := rowFormula(s.columns, c.formula, i+2)
+			if err != nil {
+				return fmt.Errorf("invalid formula of %s: %v", c.header, err)
+			}
+			if err := f.file.SetCellFormula(s.name, cell, formula); err != nil {
+				return fmt.Errorf("failed to set formula %s: %v", cell, err)
+			}
 		}
+	}
 
-		// Unmarshal the JSON back into a map
-		var m map[string]interface{}
-		if err := json.Unmarshal(b, &m); err != nil {
-			return nil, fmt.Errorf("error unmarshaling item: %v", err)
+	// Apply the number format and lock state of each column to its data range
+	if len(s.rows) == 0 {
+		return nil
+	}
+	for j, c := range s.columns {
+		style, err := newDataStyle(f.file, c, s.opt.Protection.editable(c))
+		if err != nil || style == 0 {
+			continue
 		}
-
-		data = append(data, m)
+		column, _ := excelize.ColumnNumberToName(j + 1)
+		f.file.SetCellStyle(s.name, fmt.Sprintf("%s2", column), fmt.Sprintf("%s%d", column, len(s.rows)+1), style)
 	}
+	return nil
+}
 
-	return data, nil
+// newHeaderStyle registers the header cell style, the bold white-on-blue
+// default unless the sheet sets its own
+func newHeaderStyle(f *excelize.File, style *excelize.Style) (int, error) {
+	if style != nil {
+		return f.NewStyle(style)
+	}
+	return f.NewStyle(&excelize.Style{
+		Font: &excelize.Font{
+			Bold:  true,
+			Size:  10,
+			Color: "FFFFFFFF",
+		},
+		Fill: excelize.Fill{
+			Type:    "pattern",
+			Pattern: 1,
+			Color:   []string{"3c98f2"},
+		},
+		Alignment: &excelize.Alignment{
+			Horizontal: "center",
+			Vertical:   "center",
+		},
+		Border: []excelize.Border{
+			{Type: "top", Color: "FF000000", Style: 1},
+			{Type: "left", Color: "FF000000", Style: 1},
+			{Type: "bottom", Color: "FF000000", Style: 1},
+			{Type: "right", Color: "FF000000", Style: 1},
+		},
+	})
 }